@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/Shopify/sarama"
@@ -13,6 +14,7 @@ import (
 	"github.com/spf13/viper"
 
 	md "github.com/rtlnl/phoenix/middleware"
+	"github.com/rtlnl/phoenix/pkg/blocklist"
 	"github.com/rtlnl/phoenix/pkg/cache"
 	"github.com/rtlnl/phoenix/pkg/logs"
 	"github.com/rtlnl/phoenix/public"
@@ -27,6 +29,21 @@ var (
 	recommendationESIndexFlag            = "es-index"
 	recommendationUsernameFlag           = "log-username"
 	recommendationPasswordFlag           = "log-password"
+	adminTokenFlag                       = "admin-token"
+	cacheTypeFlag                        = "cache-type"
+	cacheShardsFlag                      = "cache-shards"
+	cacheLifetimeFlag                    = "cache-lifetime"
+	cacheCleanWindowFlag                 = "cache-clean-window"
+	cacheMaxEntriesFlag                  = "cache-max-entries"
+	cacheMaxEntrySizeFlag                = "cache-max-entry-size"
+	cacheSharedLifetimeFlag              = "cache-shared-lifetime"
+)
+
+const (
+	// interval for reloading the blocklist in case a published change has been missed
+	blocklistRefreshInterval = 30 * time.Second
+	// interval between the exports of the usage of the cache to the metrics
+	cacheStatsInterval = 15 * time.Second
 )
 
 // publicCmd represents the public command
@@ -42,6 +59,8 @@ APIs for serving the personalized content.`,
 		dbPassword := viper.GetString(dbPasswordPublicFlag)
 		logType := viper.GetString(recommendationLogsFlag)
 		logDebug := viper.GetBool(logDebugFlag)
+		adminToken := viper.GetString(adminTokenFlag)
+		cacheType := viper.GetString(cacheTypeFlag)
 
 		// log level debug
 		zerolog.SetGlobalLevel(zerolog.InfoLevel)
@@ -56,15 +75,17 @@ APIs for serving the personalized content.`,
 		}
 
 		// set caching layer
-		cacheClient, err := cache.NewAllegroBigCache(cache.Shards(1024),
-			cache.LifeWindow(time.Minute*30), // mark an entry as "dead" after 30 minutes
-			cache.CleanWindow(time.Minute*5), // clean "dead" entries every 5 minutes
-			cache.MaxEntriesInWindow(1000*10*60),
-			cache.MaxEntrySize(500),
-		)
+		cacheClient, err := setCache(cacheType, redisClient)
+		if err != nil {
+			panic(err)
+		}
+
+		// evict the cached recommendations changed by the internal API
+		stopInvalidations, err := cache.WatchInvalidations(cacheClient, redisClient)
 		if err != nil {
 			panic(err)
 		}
+		defer stopInvalidations()
 
 		// create recommendation logger
 		recLogs, err := setRecommendationLogging(logType)
@@ -74,6 +95,19 @@ APIs for serving the personalized content.`,
 
 		// create metrics client
 		mc := metrics.NewPrometheus()
+		stopStats := cache.ExportStats(cacheClient, mc, cacheStatsInterval)
+		defer stopStats()
+
+		// create the global blocklist and keep it in sync with the database
+		bl, err := blocklist.NewBlocklist(redisClient)
+		if err != nil {
+			panic(err)
+		}
+		stopBlocklist, err := bl.Watch(blocklistRefreshInterval)
+		if err != nil {
+			panic(err)
+		}
+		defer stopBlocklist()
 
 		// if log type is kafka, we need to close the producer when the server stops
 		if _, ok := recLogs.(logs.KakfaLog); ok {
@@ -86,9 +120,10 @@ APIs for serving the personalized content.`,
 		middlewares = append(middlewares, md.RecommendationLogs(recLogs))
 		middlewares = append(middlewares, md.Cache(cacheClient))
 		middlewares = append(middlewares, md.Metrics(mc))
+		middlewares = append(middlewares, md.Blocklist(bl))
 
 		// create new Public api object
-		p, err := public.NewPublicAPI(middlewares...)
+		p, err := public.NewPublicAPI(adminToken, middlewares...)
 		if err != nil {
 			panic(err)
 		}
@@ -120,6 +155,14 @@ func init() {
 	f.StringP(recommendationKafkaSASLMechanismFlag, "s", "", "[LOGS] kafka sasl mechanism. Accepted values 'PLAIN', 'OAUTHBEARER', 'SCRAM-SHA-256', 'SCRAM-SHA-512', 'GSSAPI'")
 	f.StringP(recommendationESHostsFlag, "r", "", "[LOGS] elasticsearch addresses separated by comma. Example addr1:9200,addr2:9200")
 	f.StringP(recommendationESIndexFlag, "i", "", "[LOGS] elasticsearch index on where to push the data")
+	f.String(adminTokenFlag, "", "bearer token required by the admin routes. The admin routes are disabled when empty")
+	f.String(cacheTypeFlag, "allegro", "[CACHE] type of cache. Accepted type: allegro,lru,tiered")
+	f.Int(cacheShardsFlag, 1024, "[CACHE] number of shards of the allegro cache. It must be a power of two")
+	f.Duration(cacheLifetimeFlag, 30*time.Minute, "[CACHE] time after which an entry of the local cache expires")
+	f.Duration(cacheCleanWindowFlag, 5*time.Minute, "[CACHE] interval between the removals of the expired entries of the allegro cache")
+	f.Int(cacheMaxEntriesFlag, 1000*10*60, "[CACHE] number of entries of the local cache. The allegro cache uses it for the initial allocation only")
+	f.Int(cacheMaxEntrySizeFlag, 500, "[CACHE] expected size in bytes of an entry of the allegro cache used for the initial allocation")
+	f.Duration(cacheSharedLifetimeFlag, time.Hour, "[CACHE] time after which an entry of the shared cache of the tiered cache expires")
 
 	viper.BindEnv(addressPublicFlag, "ADDRESS_HOST")
 	viper.BindEnv(dbHostPublicFlag, "DB_HOST")
@@ -131,10 +174,47 @@ func init() {
 	viper.BindEnv(recommendationKafkaSASLMechanismFlag, "REC_LOGS_SASLMECHANISM")
 	viper.BindEnv(recommendationUsernameFlag, "REC_LOGS_USERNAME")
 	viper.BindEnv(recommendationPasswordFlag, "REC_LOGS_PASSWORD")
+	viper.BindEnv(adminTokenFlag, "ADMIN_TOKEN")
+	viper.BindEnv(cacheTypeFlag, "CACHE_TYPE")
+	viper.BindEnv(cacheShardsFlag, "CACHE_SHARDS")
+	viper.BindEnv(cacheLifetimeFlag, "CACHE_LIFETIME")
+	viper.BindEnv(cacheCleanWindowFlag, "CACHE_CLEAN_WINDOW")
+	viper.BindEnv(cacheMaxEntriesFlag, "CACHE_MAX_ENTRIES")
+	viper.BindEnv(cacheMaxEntrySizeFlag, "CACHE_MAX_ENTRY_SIZE")
+	viper.BindEnv(cacheSharedLifetimeFlag, "CACHE_SHARED_LIFETIME")
 
 	viper.BindPFlags(f)
 }
 
+func setCache(cacheType string, dbc db.DB) (cache.Cache, error) {
+	lifetime := viper.GetDuration(cacheLifetimeFlag)
+	maxEntries := viper.GetInt(cacheMaxEntriesFlag)
+
+	switch cacheType {
+	case "lru":
+		return cache.NewLRU(maxEntries, cache.TTL(lifetime))
+	case "tiered":
+		local, err := cache.NewLRU(maxEntries, cache.TTL(lifetime))
+		if err != nil {
+			return nil, err
+		}
+		return cache.NewTiered(local, dbc, viper.GetDuration(cacheSharedLifetimeFlag)), nil
+	case "allegro":
+		return newAllegroCache(lifetime, maxEntries)
+	default:
+		return nil, fmt.Errorf("cache type %s not supported. Accepted types: allegro,lru,tiered", cacheType)
+	}
+}
+
+func newAllegroCache(lifetime time.Duration, maxEntries int) (cache.Cache, error) {
+	return cache.NewAllegroBigCache(cache.Shards(viper.GetInt(cacheShardsFlag)),
+		cache.LifeWindow(lifetime),                                 // mark an entry as "dead" after the lifetime
+		cache.CleanWindow(viper.GetDuration(cacheCleanWindowFlag)), // clean "dead" entries every clean window
+		cache.MaxEntriesInWindow(maxEntries),
+		cache.MaxEntrySize(viper.GetInt(cacheMaxEntrySizeFlag)),
+	)
+}
+
 func setRecommendationLogging(logType string) (logs.RecommendationLog, error) {
 	switch logType {
 	case "kafka":
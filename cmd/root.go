@@ -15,6 +15,10 @@ var (
 	dbPasswordInternalFlag = "db-password-internal"
 	workerBrokerFlag       = "worker-broker-url"
 	workerPasswordFlag     = "worker-password"
+	dbHostWorkerFlag       = "db-host-worker"
+	dbPasswordWorkerFlag   = "db-password-worker"
+	sweepIntervalFlag      = "sweep-interval"
+	scheduleIntervalFlag   = "schedule-interval"
 	logDebugFlag           = "debug"
 )
 
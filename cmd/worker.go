@@ -3,10 +3,12 @@ package cmd
 import (
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/rtlnl/phoenix/models"
 	"github.com/rtlnl/phoenix/pkg/db"
 	"github.com/rtlnl/phoenix/worker"
 	"github.com/spf13/cobra"
@@ -28,6 +30,10 @@ var workerCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		brokerWorker := viper.GetString(workerBrokerFlag)
 		passwordWorker := viper.GetString(workerPasswordFlag)
+		dbHost := viper.GetString(dbHostWorkerFlag)
+		dbPassword := viper.GetString(dbPasswordWorkerFlag)
+		sweepInterval := viper.GetDuration(sweepIntervalFlag)
+		scheduleInterval := viper.GetDuration(scheduleIntervalFlag)
 
 		// instantiate Redis client
 		rc, err := db.NewRedisClient(brokerWorker, db.Password(passwordWorker))
@@ -43,6 +49,13 @@ var workerCmd = &cobra.Command{
 			os.Exit(0)
 		}
 
+		// instantiate the Redis client of the models for deleting the expired signals and applying the scheduled changes
+		dbc, err := db.NewRedisClient(dbHost, db.Password(dbPassword))
+		if err != nil {
+			panic(err)
+		}
+		defer dbc.Close()
+
 		w, err := worker.New(rc.Client, workerConsumerName, workerQueueName)
 		if err != nil {
 			panic(err)
@@ -56,6 +69,27 @@ var workerCmd = &cobra.Command{
 
 		ticker := time.NewTicker(db.TTLRefreshInterval)
 
+		// the sweeper and the scheduler run on their own so that a long run does not delay the refresh of the lock
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		runEvery(sweepInterval, done, &wg, func() {
+			n, err := models.DeleteExpiredSignals(time.Now(), dbc)
+			if err != nil {
+				log.Error().Msg(err.Error())
+			}
+			log.Info().Int("DELETED", n).Msg("expired signals deleted")
+		})
+		// the scheduled changes of the containers are applied at most one interval late
+		runEvery(scheduleInterval, done, &wg, func() {
+			n, err := models.ApplyScheduledChanges(time.Now(), dbc)
+			if err != nil {
+				log.Error().Msg(err.Error())
+			}
+			if n > 0 {
+				log.Info().Int("APPLIED", n).Msg("scheduled changes applied")
+			}
+		})
+
 		sigterm := make(chan os.Signal, 1)
 		signal.Notify(sigterm, syscall.SIGINT, syscall.SIGTERM)
 	EXITLOOP:
@@ -73,10 +107,34 @@ var workerCmd = &cobra.Command{
 				break EXITLOOP
 			}
 		}
+		close(done)
+		wg.Wait()
 		log.Info().Msg("queue close. Cleaning up...")
 	},
 }
 
+// runEvery calls fn in its own goroutine every interval until done is closed. An interval that is not positive
+// disables fn
+func runEvery(interval time.Duration, done <-chan struct{}, wg *sync.WaitGroup, fn func()) {
+	if interval <= 0 {
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
 func init() {
 	rootCmd.AddCommand(workerCmd)
 
@@ -84,9 +142,17 @@ func init() {
 
 	f.String(workerBrokerFlag, "127.0.0.1:6379", "broker url for the workers")
 	f.String(workerPasswordFlag, "", "broker password")
+	f.String(dbHostWorkerFlag, "127.0.0.1:6379", "database host for deleting the expired signals and applying the scheduled changes")
+	f.String(dbPasswordWorkerFlag, "", "database password")
+	f.Duration(scheduleIntervalFlag, 10*time.Second, "interval between the checks of the scheduled changes of the containers. 0 disables the changes")
+	f.Duration(sweepIntervalFlag, time.Minute, "interval between the deletions of the expired signals. 0 disables the deletion")
 
 	viper.BindEnv(workerBrokerFlag, "WORKER_BROKER_URL")
 	viper.BindEnv(workerPasswordFlag, "WORKER_PASSWORD")
+	viper.BindEnv(dbHostWorkerFlag, "DB_HOST")
+	viper.BindEnv(dbPasswordWorkerFlag, "DB_PASSWORD")
+	viper.BindEnv(sweepIntervalFlag, "SWEEP_INTERVAL")
+	viper.BindEnv(scheduleIntervalFlag, "SCHEDULE_INTERVAL")
 
 	viper.BindPFlags(f)
 }
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -17,11 +18,13 @@ import (
 )
 
 // BatchRequest is the object that represents the payload of the request for the batch endpoints
-// Conditions: Data takes precedence in case also DataLocation is specified
+// Conditions: Data takes precedence in case also DataLocation is specified. Data is upserted in the
+// current version of the model while DataLocation creates a new version
 type BatchRequest struct {
 	ModelName    string       `json:"modelName" binding:"required"`
 	Data         []batch.Data `json:"data" description:"used for uploading some information directly from the request"`
 	DataLocation string       `json:"dataLocation" description:"used for specifying where the data lives in S3"`
+	ExpiresAt    *time.Time   `json:"expiresAt" description:"time after which the signals uploaded directly are no longer served and deleted. The lines of the S3 file set their own expiresAt"`
 }
 
 // BatchResponse is the object that represents the payload of the response for the batch endpoints
@@ -37,7 +40,7 @@ type BatchStatusResponseError struct {
 	Line                []models.LineError `json:"line" description:"shows the line error and the reason, i.e. {'100', 'reason': 'validation message'}"`
 }
 
-// BatchBulkResponse is the object that represents the payload of the response when uploading from S3
+// BatchBulkResponse is the object that represents the payload of the response when submitting a job to the worker
 type BatchBulkResponse struct {
 	BatchID string `json:"batchId"`
 }
@@ -61,7 +64,11 @@ func Batch(c *gin.Context) {
 	// upload data from request itself
 	bo := batch.NewOperator(dbc, m)
 	if len(br.Data) > 0 && br.Data != nil {
-		ln, due, err := bo.UploadDataDirectly(br.Data)
+		if err := models.ValidateExpiry(br.ExpiresAt); err != nil {
+			utils.ResponseError(c, http.StatusBadRequest, err)
+			return
+		}
+		ln, due, err := bo.UploadDataDirectly(br.Data, br.ExpiresAt)
 		if err != nil {
 			utils.ResponseError(c, http.StatusInternalServerError, err)
 			return
@@ -69,13 +76,8 @@ func Batch(c *gin.Context) {
 		utils.Response(c, http.StatusCreated, &BatchResponse{NumberOfLines: ln, ErrorRecords: due})
 		return
 	}
-	// truncate eventual old data
-	if err := dbc.DropTable(br.ModelName); err != nil {
-		utils.ResponseError(c, http.StatusInternalServerError, err)
-		return
-	}
-	log.Info().Str("DELETE", fmt.Sprintf("table %s", br.ModelName))
-	// upload data from S3 file
+	// upload data from S3 file in a new version of the model. The current data keeps
+	// being served until the upload is completed
 	bucket, key := utils.StripS3URL(br.DataLocation)
 	// generate batchID
 	batchID := uuid.New().String()
@@ -93,6 +95,7 @@ func Batch(c *gin.Context) {
 	}
 	// create task payload to send to the queue
 	taskPayload := &worker.TaskPayload{
+		Type:         worker.TaskUpload,
 		DBURL:        os.Getenv("DB_HOST"),
 		DBPassword:   os.Getenv("DB_PASSWORD"),
 		AWSRegion:    os.Getenv("S3_REGION"),
@@ -152,3 +155,65 @@ func BatchStatus(c *gin.Context) {
 		utils.Response(c, http.StatusOK, &BatchStatusResponse{Status: status})
 	}
 }
+
+// BatchRemoveItemRequest is the object that represents the payload of the request for removing an item from a model
+type BatchRemoveItemRequest struct {
+	ModelName string `json:"modelName" binding:"required"`
+	ItemID    string `json:"itemId" description:"ID of the item to remove from all the signals of the model" binding:"required"`
+}
+
+// BatchRemoveItem submits the job for removing an item from all the signals of a model
+func BatchRemoveItem(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+	wrk := c.MustGet("Worker").(*worker.Worker)
+
+	var br BatchRemoveItemRequest
+	if err := c.BindJSON(&br); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+	// retrieve the model
+	m, err := models.GetModel(br.ModelName, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+	// generate jobID
+	jobID := uuid.New().String()
+	// write to DB that it's queued
+	bo := batch.NewOperator(dbc, m)
+	if err := bo.SetRemovalProgress(jobID, batch.RemovalProgress{Status: batch.BulkQueued, ModelName: m.Name, ItemID: br.ItemID}); err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, err)
+		return
+	}
+	// create task payload to send to the queue
+	taskPayload := &worker.TaskPayload{
+		Type:       worker.TaskRemoveItem,
+		DBURL:      os.Getenv("DB_HOST"),
+		DBPassword: os.Getenv("DB_PASSWORD"),
+		ModelName:  br.ModelName,
+		BatchID:    jobID,
+		ItemID:     br.ItemID,
+	}
+	// publish message to the queue
+	if err := wrk.Publish(taskPayload); err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Info().Str("REMOVE", fmt.Sprintf("started jobId %s for item %s", jobID, br.ItemID)).Str("MODEL", fmt.Sprintf("name %s", br.ModelName))
+
+	utils.Response(c, http.StatusCreated, &BatchBulkResponse{BatchID: jobID})
+}
+
+// BatchRemoveItemStatus returns the progress of the removal of an item
+func BatchRemoveItemStatus(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	p, err := batch.GetRemovalProgress(c.Param("id"), dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+	utils.Response(c, http.StatusOK, &p)
+}
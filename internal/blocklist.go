@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/db"
+	"github.com/rtlnl/phoenix/utils"
+)
+
+// ManagementBlocklistRequest is the object that represents the payload of the request for the /management/blocklist endpoints
+type ManagementBlocklistRequest struct {
+	Items []string `json:"items" description:"IDs of the items to block or unblock" binding:"required"`
+}
+
+// ManagementBlocklistResponse is the object that represents the payload of the response for the /management/blocklist endpoints
+type ManagementBlocklistResponse struct {
+	Count   int      `json:"count"`
+	Items   []string `json:"items" description:"IDs of the items currently blocked"`
+	Message string   `json:"message" description:"summary of the action just taken"`
+}
+
+// GetBlocklist returns all the items of the global blocklist
+func GetBlocklist(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	items, err := models.GetBlockedItems(dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementBlocklistResponse{
+		Count:   len(items),
+		Items:   items,
+		Message: "blocklist fetched",
+	})
+}
+
+// BlockItems adds the items to the global blocklist. The public instances stop serving them within seconds
+func BlockItems(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mb ManagementBlocklistRequest
+	if err := c.BindJSON(&mb); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := models.BlockItems(mb.Items, dbc); err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondBlocklist(c, dbc, "items blocked")
+}
+
+// UnblockItems removes the items from the global blocklist
+func UnblockItems(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mb ManagementBlocklistRequest
+	if err := c.BindJSON(&mb); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := models.UnblockItems(mb.Items, dbc); err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	respondBlocklist(c, dbc, "items unblocked")
+}
+
+// respondBlocklist responds with the items currently blocked
+func respondBlocklist(c *gin.Context, dbc db.DB, message string) {
+	items, err := models.GetBlockedItems(dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementBlocklistResponse{
+		Count:   len(items),
+		Items:   items,
+		Message: message,
+	})
+}
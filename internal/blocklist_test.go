@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockItems(t *testing.T) {
+	rb, err := json.Marshal(&ManagementBlocklistRequest{Items: []string{"taken-down", "expired"}})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/management/blocklist/", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"count\":2,\"items\":[\"expired\",\"taken-down\"],\"message\":\"items blocked\"}", string(b))
+
+	rb, err = json.Marshal(&ManagementBlocklistRequest{Items: []string{"expired"}})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err = MockRequest(http.MethodDelete, "/v1/management/blocklist/", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"count\":1,\"items\":[\"taken-down\"],\"message\":\"items unblocked\"}", string(b))
+
+	code, body, err = MockRequest(http.MethodGet, "/v1/management/blocklist/", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"count\":1,\"items\":[\"taken-down\"],\"message\":\"blocklist fetched\"}", string(b))
+}
+
+func TestUnblockItemsNotBlocked(t *testing.T) {
+	rb, err := json.Marshal(&ManagementBlocklistRequest{Items: []string{"never-blocked"}})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodDelete, "/v1/management/blocklist/", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "{\"error\":\"item never-blocked is not blocked\"}", string(b))
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 	"github.com/rtlnl/phoenix/models"
 	"github.com/rtlnl/phoenix/pkg/db"
 	"github.com/rtlnl/phoenix/utils"
@@ -12,9 +13,22 @@ import (
 
 // ManagementContainerRequest handles the request from the client
 type ManagementContainerRequest struct {
-	PublicationPoint string   `json:"publicationPoint" binding:"required"`
-	Campaign         string   `json:"campaign" binding:"required"`
-	Models           []string `json:"models"`
+	PublicationPoint string         `json:"publicationPoint" binding:"required"`
+	Campaign         string         `json:"campaign" binding:"required"`
+	Models           []string       `json:"models"`
+	Weights          map[string]int `json:"weights" description:"traffic weight per linked model, i.e. {'modelA': 80, 'modelB': 20}"`
+	DefaultModel     string         `json:"defaultModel" description:"linked model served when no model is selected by the request or the traffic split"`
+	FallbackModels   []string       `json:"fallbackModels" description:"ordered list of models tried when the selected model has no entry for the signal"`
+	Rules            *models.Rules  `json:"rules" description:"business rules applied to the recommendations before serving them"`
+	Blend            *models.Blend  `json:"blend" description:"strategy used for serving the recommendations of all the linked models at once"`
+}
+
+// ManagementContainerMoveRequest handles the request for moving a container to a new publication point and campaign
+type ManagementContainerMoveRequest struct {
+	PublicationPoint    string `json:"publicationPoint" binding:"required"`
+	Campaign            string `json:"campaign" binding:"required"`
+	NewPublicationPoint string `json:"newPublicationPoint" binding:"required"`
+	NewCampaign         string `json:"newCampaign" binding:"required"`
 }
 
 // ManagementContainerResponse handles the response object to the client
@@ -66,14 +80,54 @@ func CreateContainer(c *gin.Context) {
 		return
 	}
 
+	// a container that cannot be configured as requested is not kept, so that the request can be retried
+	if err := configureContainer(&container, mc, dbc); err != nil {
+		if err := container.DeleteContainer(dbc); err != nil {
+			log.Error().Str("CONTAINER", models.ContainerUniqueName(mc.PublicationPoint, mc.Campaign)).Msgf("could not delete the container. error: %s", err.Error())
+		}
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
 	utils.Response(c, http.StatusCreated, &ManagementContainerResponse{
 		Container: container,
 		Message:   "container created",
 	})
 }
 
-// EmptyContainer truncate the container's data
-func EmptyContainer(c *gin.Context) {
+// configureContainer sets the weights, the model chain, the rules and the blend of the request
+func configureContainer(container *models.Container, mc ManagementContainerRequest, dbc db.DB) error {
+	// split the traffic between the models if requested
+	if len(mc.Weights) > 0 {
+		if err := container.SetWeights(mc.Weights, dbc); err != nil {
+			return err
+		}
+	}
+
+	// set the default model and the fallback chain if requested
+	if mc.DefaultModel != "" || len(mc.FallbackModels) > 0 {
+		if err := container.SetModelChain(mc.DefaultModel, mc.FallbackModels, dbc); err != nil {
+			return err
+		}
+	}
+
+	// set the business rules if requested
+	if mc.Rules != nil {
+		if err := container.SetRules(*mc.Rules, dbc); err != nil {
+			return err
+		}
+	}
+
+	// blend the linked models if requested
+	if mc.Blend != nil {
+		return container.SetBlend(*mc.Blend, dbc)
+	}
+	return nil
+}
+
+// DeleteContainer removes the container from the database. The message of the response is kept as "container
+// empty" for the clients of the original route
+func DeleteContainer(c *gin.Context) {
 	dbc := c.MustGet("DB").(db.DB)
 
 	var mc ManagementContainerRequest
@@ -88,7 +142,7 @@ func EmptyContainer(c *gin.Context) {
 		return
 	}
 
-	// empty model from database
+	// delete container from database
 	if err := container.DeleteContainer(dbc); err != nil {
 		utils.ResponseError(c, http.StatusInternalServerError, err)
 		return
@@ -100,6 +154,61 @@ func EmptyContainer(c *gin.Context) {
 	})
 }
 
+// EmptyContainer unlinks all the models from the container but leaves the container in the database
+func EmptyContainer(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mc ManagementContainerRequest
+	if err := c.BindJSON(&mc); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	container, err := models.GetContainer(mc.PublicationPoint, mc.Campaign, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	// empty container in the database
+	if err := container.EmptyContainer(dbc); err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementContainerResponse{
+		Container: container,
+		Message:   "container emptied",
+	})
+}
+
+// MoveContainer changes the publication point and the campaign of an existing container
+func MoveContainer(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mc ManagementContainerMoveRequest
+	if err := c.BindJSON(&mc); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	container, err := models.GetContainer(mc.PublicationPoint, mc.Campaign, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	if err := container.Move(mc.NewPublicationPoint, mc.NewCampaign, dbc); err != nil {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementContainerResponse{
+		Container: container,
+		Message:   "container moved",
+	})
+}
+
 // LinkModel attaches the specified models in input to an existing container
 func LinkModel(c *gin.Context) {
 	dbc := c.MustGet("DB").(db.DB)
@@ -135,6 +244,194 @@ func LinkModel(c *gin.Context) {
 	})
 }
 
+// UnlinkModel detaches the specified models in input from an existing container
+func UnlinkModel(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mc ManagementContainerRequest
+	if err := c.BindJSON(&mc); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(mc.Models) <= 0 {
+		utils.ResponseError(c, http.StatusBadRequest, errors.New("no models to unlink from the container"))
+		return
+	}
+
+	// get the existing container
+	container, err := models.GetContainer(mc.PublicationPoint, mc.Campaign, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	if err := container.UnlinkModel(mc.Models, dbc); err != nil {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementContainerResponse{
+		Container: container,
+		Message:   "model unlinked from container",
+	})
+}
+
+// ReorderModels changes the order of the models linked to an existing container
+func ReorderModels(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mc ManagementContainerRequest
+	if err := c.BindJSON(&mc); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// get the existing container
+	container, err := models.GetContainer(mc.PublicationPoint, mc.Campaign, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	if err := container.ReorderModels(mc.Models, dbc); err != nil {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementContainerResponse{
+		Container: container,
+		Message:   "models reordered",
+	})
+}
+
+// SetWeights updates the traffic weights of the models linked to an existing container
+func SetWeights(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mc ManagementContainerRequest
+	if err := c.BindJSON(&mc); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// get the existing container
+	container, err := models.GetContainer(mc.PublicationPoint, mc.Campaign, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	// store the new weights
+	if err := container.SetWeights(mc.Weights, dbc); err != nil {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementContainerResponse{
+		Container: container,
+		Message:   "weights updated",
+	})
+}
+
+// SetModelChain updates the default model and the fallback models of an existing container
+func SetModelChain(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mc ManagementContainerRequest
+	if err := c.BindJSON(&mc); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// get the existing container
+	container, err := models.GetContainer(mc.PublicationPoint, mc.Campaign, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	// store the new chain
+	if err := container.SetModelChain(mc.DefaultModel, mc.FallbackModels, dbc); err != nil {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementContainerResponse{
+		Container: container,
+		Message:   "model chain updated",
+	})
+}
+
+// SetRules updates the business rules of an existing container. A request without rules removes them
+func SetRules(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mc ManagementContainerRequest
+	if err := c.BindJSON(&mc); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// get the existing container
+	container, err := models.GetContainer(mc.PublicationPoint, mc.Campaign, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	var rules models.Rules
+	if mc.Rules != nil {
+		rules = *mc.Rules
+	}
+
+	// store the new rules
+	if err := container.SetRules(rules, dbc); err != nil {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementContainerResponse{
+		Container: container,
+		Message:   "rules updated",
+	})
+}
+
+// SetBlend updates the strategy used for blending the linked models of an existing container. A request without
+// blend serves again one model per request
+func SetBlend(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mc ManagementContainerRequest
+	if err := c.BindJSON(&mc); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// get the existing container
+	container, err := models.GetContainer(mc.PublicationPoint, mc.Campaign, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	var blend models.Blend
+	if mc.Blend != nil {
+		blend = *mc.Blend
+	}
+
+	// store the new blend
+	if err := container.SetBlend(blend, dbc); err != nil {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementContainerResponse{
+		Container: container,
+		Message:   "blend updated",
+	})
+}
+
 // ManagementContainersResponse handles the response when there are multiple containers
 type ManagementContainersResponse struct {
 	Count      int                `json:"count"`
@@ -117,6 +117,59 @@ func TestCreateContainerAlreadyExists(t *testing.T) {
 	assert.Equal(t, "{\"error\":\"container with publication point dog and campaign vizsla already exists\"}", string(b))
 }
 
+func TestCreateContainerInvalidWeights(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	m, err := models.NewModel("latte", "", []string{"cup"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+
+	mmc := &ManagementContainerRequest{
+		PublicationPoint: "milk",
+		Campaign:         "breakfast",
+		Models:           []string{"latte"},
+		Weights:          map[string]int{"cappuccino": 100},
+	}
+
+	rb, err := json.Marshal(mmc)
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/management/containers/", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "{\"error\":\"model with name cappuccino is not linked to the container\"}", body.String())
+
+	// the container is not kept and the request can be retried
+	assert.False(t, models.ContainerExists("milk", "breakfast", dbc))
+
+	mmc.Weights = map[string]int{"latte": 100}
+	rb, err = json.Marshal(mmc)
+	if err != nil {
+		t.Fail()
+	}
+
+	code, _, err = MockRequest(http.MethodPost, "/v1/management/containers/", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+	assert.Equal(t, http.StatusCreated, code)
+
+	container, err := models.GetContainer("milk", "breakfast", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	container.DeleteContainer(dbc)
+}
+
 func TestCreateContainerFailValidationCampaign(t *testing.T) {
 	r, err := createManagementContainerRequest("videoland", "", nil)
 	if err != nil {
@@ -213,6 +266,9 @@ func TestEmptyContainer(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, code)
 	assert.Equal(t, "{\"container\":{\"publicationPoint\":\"cat\",\"campaign\":\"anubi\",\"models\":[\"egypt\"]},\"message\":\"container empty\"}", string(b))
+
+	// the route deletes the container
+	assert.Equal(t, false, models.ContainerExists("cat", "anubi", dbc))
 }
 
 func TestEmptyContainerFailValidation(t *testing.T) {
@@ -293,3 +349,347 @@ func TestLinkModel(t *testing.T) {
 	assert.Equal(t, http.StatusOK, code)
 	assert.Equal(t, "{\"container\":{\"publicationPoint\":\"channel\",\"campaign\":\"dart\",\"models\":[\"hello\",\"world\"]},\"message\":\"model linked to container\"}", string(b))
 }
+
+func TestSetWeights(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	if _, err := models.NewModel("espresso", "", []string{"bean"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewModel("ristretto", "", []string{"bean"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewContainer("coffee", "morning", []string{"espresso", "ristretto"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	mmc := &ManagementContainerRequest{
+		PublicationPoint: "coffee",
+		Campaign:         "morning",
+		Weights:          map[string]int{"espresso": 70, "ristretto": 30},
+	}
+
+	rb, err := json.Marshal(mmc)
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/containers/weights", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"container\":{\"publicationPoint\":\"coffee\",\"campaign\":\"morning\",\"models\":[\"espresso\",\"ristretto\"],\"weights\":{\"espresso\":70,\"ristretto\":30}},\"message\":\"weights updated\"}", string(b))
+}
+
+func TestSetModelChain(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	if _, err := models.NewModel("croissant", "", []string{"butter"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewModel("baguette", "", []string{"butter"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewContainer("bakery", "breakfast", []string{"croissant"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	mmc := &ManagementContainerRequest{
+		PublicationPoint: "bakery",
+		Campaign:         "breakfast",
+		DefaultModel:     "croissant",
+		FallbackModels:   []string{"baguette"},
+	}
+
+	rb, err := json.Marshal(mmc)
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/containers/model-chain", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"container\":{\"publicationPoint\":\"bakery\",\"campaign\":\"breakfast\",\"models\":[\"croissant\"],\"defaultModel\":\"croissant\",\"fallbackModels\":[\"baguette\"]},\"message\":\"model chain updated\"}", string(b))
+}
+
+func TestEmptyContainerModels(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := models.NewModel("salmon", "", []string{"fishId"}, dbc); err != nil {
+		t.FailNow()
+	}
+	if _, err := models.NewContainer("fish", "salmon", []string{"salmon"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	r, err := createManagementContainerRequest("fish", "salmon", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/containers/empty", r)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	// the response contains the container without models, which is still in the database
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"container\":{\"publicationPoint\":\"fish\",\"campaign\":\"salmon\"},\"message\":\"container emptied\"}", string(b))
+
+	stored, err := models.GetContainer("fish", "salmon", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Empty(t, stored.Models)
+}
+
+func TestMoveContainer(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := models.NewContainer("bird", "parrot", nil, dbc); err != nil {
+		t.FailNow()
+	}
+
+	mmc := &ManagementContainerMoveRequest{
+		PublicationPoint:    "bird",
+		Campaign:            "parrot",
+		NewPublicationPoint: "bird",
+		NewCampaign:         "macaw",
+	}
+
+	rb, err := json.Marshal(mmc)
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/containers/move", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"container\":{\"publicationPoint\":\"bird\",\"campaign\":\"macaw\"},\"message\":\"container moved\"}", string(b))
+}
+
+func TestUnlinkModel(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	for _, m := range []string{"oak", "birch"} {
+		if _, err := models.NewModel(m, "", []string{"leaf"}, dbc); err != nil {
+			t.FailNow()
+		}
+	}
+
+	if _, err := models.NewContainer("forest", "trees", []string{"oak", "birch"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	r, err := createManagementContainerRequest("forest", "trees", []string{"oak"})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/containers/unlink-model", r)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"container\":{\"publicationPoint\":\"forest\",\"campaign\":\"trees\",\"models\":[\"birch\"]},\"message\":\"model unlinked from container\"}", string(b))
+}
+
+func TestReorderModels(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	for _, m := range []string{"rose", "tulip"} {
+		if _, err := models.NewModel(m, "", []string{"petal"}, dbc); err != nil {
+			t.FailNow()
+		}
+	}
+
+	if _, err := models.NewContainer("garden", "flowers", []string{"rose", "tulip"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	r, err := createManagementContainerRequest("garden", "flowers", []string{"tulip", "daisy"})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/containers/reorder-models", r)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "{\"error\":\"model with name daisy not found\"}", string(b))
+
+	r, err = createManagementContainerRequest("garden", "flowers", []string{"tulip", "rose"})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err = MockRequest(http.MethodPut, "/v1/management/containers/reorder-models", r)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"container\":{\"publicationPoint\":\"garden\",\"campaign\":\"flowers\",\"models\":[\"tulip\",\"rose\"]},\"message\":\"models reordered\"}", string(b))
+}
+
+func TestSetRules(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := models.NewContainer("news", "editorial", nil, dbc); err != nil {
+		t.FailNow()
+	}
+
+	minScore := 0.5
+	mmc := &ManagementContainerRequest{
+		PublicationPoint: "news",
+		Campaign:         "editorial",
+		Rules: &models.Rules{
+			ExcludeTypes: []string{"clip"},
+			BlockedItems: []string{"123"},
+			MinScore:     &minScore,
+		},
+	}
+
+	rb, err := json.Marshal(mmc)
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/containers/rules", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"container\":{\"publicationPoint\":\"news\",\"campaign\":\"editorial\",\"rules\":{\"excludeTypes\":[\"clip\"],\"blockedItems\":[\"123\"],\"minScore\":0.5}},\"message\":\"rules updated\"}", string(b))
+}
+
+func TestSetBlend(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := models.NewModel("spruce", "", []string{"userId"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewContainer("news", "blended", []string{"spruce"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	mmc := &ManagementContainerRequest{
+		PublicationPoint: "news",
+		Campaign:         "blended",
+		Blend:            &models.Blend{Strategy: models.BlendRoundRobin},
+	}
+
+	rb, err := json.Marshal(mmc)
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/containers/blend", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"container\":{\"publicationPoint\":\"news\",\"campaign\":\"blended\",\"models\":[\"spruce\"],\"blend\":{\"strategy\":\"round-robin\"}},\"message\":\"blend updated\"}", string(b))
+}
+
+func TestSetBlendWrongStrategy(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := models.NewContainer("news", "unblended", nil, dbc); err != nil {
+		t.FailNow()
+	}
+
+	mmc := &ManagementContainerRequest{
+		PublicationPoint: "news",
+		Campaign:         "unblended",
+		Blend:            &models.Blend{Strategy: "random"},
+	}
+
+	rb, err := json.Marshal(mmc)
+	if err != nil {
+		t.Fail()
+	}
+
+	code, _, err := MockRequest(http.MethodPut, "/v1/management/containers/blend", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+}
@@ -36,8 +36,11 @@ func NewInternalAPI(middlewares ...gin.HandlerFunc) (*Internal, error) {
 	v1 := r.Group("v1")
 	v1.POST("/batch", Batch)
 	v1.GET("/batch/status/:id", BatchStatus)
+	v1.POST("/batch/remove-item", BatchRemoveItem)
+	v1.GET("/batch/remove-item/status/:id", BatchRemoveItemStatus)
 
 	sc := v1.Group("/streaming")
+	sc.GET("/", GetStreaming)
 	sc.POST("/", CreateStreaming)
 	sc.PUT("/", UpdateStreaming)
 	sc.DELETE("/", DeleteStreaming)
@@ -50,16 +53,38 @@ func NewInternalAPI(middlewares ...gin.HandlerFunc) (*Internal, error) {
 	mc := mg.Group("/containers")
 	mc.GET("/", GetContainer)
 	mc.POST("/", CreateContainer)
-	mc.DELETE("/", EmptyContainer)
+	mc.DELETE("/", DeleteContainer)
+	mc.PUT("/empty", EmptyContainer)
+	mc.PUT("/move", MoveContainer)
 	mc.GET("/all", GetAllContainers)
 	mc.PUT("/link-model", LinkModel)
+	mc.PUT("/unlink-model", UnlinkModel)
+	mc.PUT("/reorder-models", ReorderModels)
+	mc.PUT("/weights", SetWeights)
+	mc.PUT("/model-chain", SetModelChain)
+	mc.PUT("/rules", SetRules)
+	mc.PUT("/blend", SetBlend)
+	mc.GET("/schedules", GetSchedules)
+	mc.POST("/schedules", CreateSchedule)
+	mc.DELETE("/schedules", CancelSchedule)
+
+	// Blocklist routes
+	mb := mg.Group("/blocklist")
+	mb.GET("/", GetBlocklist)
+	mb.POST("/", BlockItems)
+	mb.DELETE("/", UnblockItems)
 
 	// Model routes
 	mm := mg.Group("/models")
 	mm.GET("/", GetModel)
 	mm.POST("/", CreateModel)
+	mm.PUT("/", UpdateModel)
 	mm.DELETE("/", EmptyModel)
 	mm.GET("/preview", GetDataPreview)
+	mm.PUT("/rollback", RollbackModel)
+	mm.GET("/versions", GetModelVersions)
+	mm.PUT("/versions/activate", ActivateModelVersion)
+	mm.DELETE("/versions", DeleteModelVersions)
 	mm.GET("/all", GetAllModels)
 
 	return &Internal{
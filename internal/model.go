@@ -2,6 +2,7 @@ package internal
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -16,9 +17,14 @@ import (
 
 // ManagementModelRequest is the object that represents the payload of the request for the /management/model endpoints
 type ManagementModelRequest struct {
-	Name         string   `json:"name" description:"name of the model" binding:"required"`
-	SignalOrder  []string `json:"signalOrder" description:"list of ordered signals" binding:"required"`
-	Concatenator string   `json:"concatenator" description:"character used as concatenator for SignalOrder {'|', '#', '_', '-'}"`
+	Name         string                       `json:"name" description:"name of the model" binding:"required"`
+	SignalOrder  []string                     `json:"signalOrder" description:"list of ordered signals. Required for personalized models"`
+	Concatenator string                       `json:"concatenator" description:"character used as concatenator for SignalOrder {'|', '#', '_', '-'}"`
+	SignalSchema map[string]models.SignalPart `json:"signalSchema" description:"values accepted by each entry of the signalOrder, i.e. {'userId': {'type': 'integer'}}"`
+	ItemSchema   *models.ItemSchema           `json:"itemSchema" description:"schema of the recommended items accepted by the model"`
+	SortOnUpload *bool                        `json:"sortOnUpload" description:"sort the recommendations by score when uploading them in batch"`
+	Kind         string                       `json:"kind" description:"kind of the model. Empty for personalized models or 'popular' for a global list of items"`
+	Migrate      bool                         `json:"migrate" description:"when updating the model, migrate the data to the new signal format instead of deleting it"`
 }
 
 // ManagementModelResponse is the object that represents the payload of the response for the /management/model endpoints
@@ -29,14 +35,29 @@ type ManagementModelResponse struct {
 
 var (
 	concatenatorList = []string{"|", "#", "_", "-"}
+	kindList         = []string{"", models.KindPopular}
 	validate         *validator.Validate
 )
 
 // ManagementModelRequestStructureValidation validates structure and content
 func ManagementModelRequestStructureValidation(sl validator.StructLevel) {
 	request := sl.Current().Interface().(ManagementModelRequest)
-	// Enforces the need of a separator when more than one element
-	if len(request.SignalOrder) > 1 && !utils.StringInSlice(request.Concatenator, concatenatorList) {
+	if strings.Contains(request.Name, "@") {
+		sl.ReportError(request.Name, "name", "Name", "wrongName", "")
+		return
+	}
+	if !utils.StringInSlice(request.Kind, kindList) {
+		sl.ReportError(request.Kind, "kind", "Kind", "wrongKind", "")
+		return
+	}
+	// popular models do not have any signal
+	if request.Kind == models.KindPopular {
+		return
+	}
+	// Enforces the need of signals and of a separator when more than one element
+	if len(request.SignalOrder) == 0 {
+		sl.ReportError(request.SignalOrder, "SignalOrder", "SignalOrder", "required", "")
+	} else if len(request.SignalOrder) > 1 && !utils.StringInSlice(request.Concatenator, concatenatorList) {
 		sl.ReportError(request.Concatenator, "concatenator", "", "wrongConcatenator", "")
 	} else if len(request.SignalOrder) == 1 && len(request.Concatenator) > 0 {
 		sl.ReportError(request.Concatenator, "concatenator", "", "noConcatenatorNeeded", "")
@@ -57,6 +78,10 @@ func ValidationConcatenationErrorMsg(err error) error {
 		err = errors.New("for two or more signalOrder, a concatenator character from this list is mandatory: [" + strings.Join(concatenatorList, ", ") + "]")
 	case strings.Contains(err.Error(), "noConcatenatorNeeded"):
 		err = errors.New("for one signalOrder no concatenator character is required")
+	case strings.Contains(err.Error(), "wrongName"):
+		err = errors.New("the name of the model cannot contain the character @")
+	case strings.Contains(err.Error(), "wrongKind"):
+		err = errors.New("the kind of the model must be either empty or " + models.KindPopular)
 	}
 	return err
 }
@@ -102,18 +127,101 @@ func CreateModel(c *gin.Context) {
 		return
 	}
 
-	m, err := models.NewModel(mm.Name, mm.Concatenator, mm.SignalOrder, dbc)
+	// validate the schema upfront for not leaving a model without it
+	if err := models.ValidateSignalSchema(mm.SignalSchema, mm.SignalOrder); err != nil {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var m models.Model
+	var err error
+	if mm.Kind == models.KindPopular {
+		m, err = models.NewPopularModel(mm.Name, dbc)
+	} else {
+		m, err = models.NewModel(mm.Name, mm.Concatenator, mm.SignalOrder, dbc)
+	}
 	if err != nil {
 		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
 		return
 	}
 
+	// store the schema of the signal parts if requested
+	if len(mm.SignalSchema) > 0 {
+		if err := m.SetSignalSchema(mm.SignalSchema, dbc); err != nil {
+			utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+
+	// store the schema of the items if requested
+	if mm.ItemSchema != nil {
+		if err := m.SetItemSchema(*mm.ItemSchema, dbc); err != nil {
+			utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+
+	// sort the uploaded data if requested
+	if mm.SortOnUpload != nil && *mm.SortOnUpload {
+		if err := m.SetSortOnUpload(true, dbc); err != nil {
+			utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+
 	utils.Response(c, http.StatusCreated, &ManagementModelResponse{
 		Model:   m,
 		Message: "model created",
 	})
 }
 
+// UpdateModel changes the signalOrder, the concatenator, the schemas and the sorting of an existing model. The
+// schemas and the sorting omitted in the request are left unchanged
+func UpdateModel(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mm ManagementModelRequest
+	if err := c.BindJSON(&mm); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, ValidationConcatenationErrorMsg(err))
+		return
+	}
+
+	m, err := models.GetModel(mm.Name, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	// the request is validated against the kind of the stored model
+	if mm.Kind != m.Kind {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, fmt.Errorf("the kind of the model %s cannot be changed", m.Name))
+		return
+	}
+
+	if err := ManagementModelRequestValidation(&mm); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, ValidationConcatenationErrorMsg(err))
+		return
+	}
+
+	// the data is migrated or deleted only when the signal format changes
+	if err := m.Update(models.ModelUpdate{
+		SignalOrder:  mm.SignalOrder,
+		Concatenator: mm.Concatenator,
+		SignalSchema: mm.SignalSchema,
+		ItemSchema:   mm.ItemSchema,
+		SortOnUpload: mm.SortOnUpload,
+		Migrate:      mm.Migrate,
+	}, dbc); err != nil {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementModelResponse{
+		Model:   m,
+		Message: "model updated",
+	})
+}
+
 // EmptyModel truncate the content of a model but leave the model in the database
 func EmptyModel(c *gin.Context) {
 	dbc := c.MustGet("DB").(db.DB)
@@ -147,6 +255,130 @@ func EmptyModel(c *gin.Context) {
 	})
 }
 
+// ManagementModelVersionRequest is the object that represents the payload of the request for the versions of a model
+type ManagementModelVersionRequest struct {
+	Name      string `json:"name" description:"name of the model" binding:"required"`
+	Version   string `json:"version" description:"batch ID of the version to activate"`
+	Retention int    `json:"retention" description:"number of versions to keep when deleting the old ones"`
+}
+
+// ManagementModelVersionsResponse is the object that represents the payload of the response for the versions of a model
+type ManagementModelVersionsResponse struct {
+	Version  string                `json:"version" description:"version of the data currently served by the model"`
+	Versions []models.ModelVersion `json:"versions" description:"history of the versions of the model"`
+	Message  string                `json:"message" description:"summary of the action just taken"`
+}
+
+// GetModelVersions returns the history of the versions of the model
+func GetModelVersions(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	// read from params in url
+	mn := c.Query("name")
+	if mn == "" {
+		utils.ResponseError(c, http.StatusBadRequest, errors.New("missing parameters in url for searching the model"))
+		return
+	}
+
+	m, err := models.GetModel(mn, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementModelVersionsResponse{
+		Version:  m.Version,
+		Versions: m.Versions,
+		Message:  "versions fetched",
+	})
+}
+
+// ActivateModelVersion re-points the model to a version of its history
+func ActivateModelVersion(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mv ManagementModelVersionRequest
+	if err := c.BindJSON(&mv); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+	if mv.Version == "" {
+		utils.ResponseError(c, http.StatusBadRequest, errors.New("missing version to activate"))
+		return
+	}
+
+	m, err := models.GetModel(mv.Name, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	if err := m.ActivateVersion(mv.Version, dbc); err != nil {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementModelResponse{
+		Model:   m,
+		Message: "version activated",
+	})
+}
+
+// DeleteModelVersions removes the data of the versions exceeding the retention count
+func DeleteModelVersions(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mv ManagementModelVersionRequest
+	if err := c.BindJSON(&mv); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	m, err := models.GetModel(mv.Name, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	if _, err := m.DeleteOldVersions(mv.Retention, dbc); err != nil {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementModelVersionsResponse{
+		Version:  m.Version,
+		Versions: m.Versions,
+		Message:  "old versions deleted",
+	})
+}
+
+// RollbackModel re-points the model to the version of the data served before the current one
+func RollbackModel(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var mv ManagementModelVersionRequest
+	if err := c.BindJSON(&mv); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	m, err := models.GetModel(mv.Name, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	if err := m.Rollback(dbc); err != nil {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementModelResponse{
+		Model:   m,
+		Message: "model rolled back",
+	})
+}
+
 // ManagementModelsResponse handles the response when multiple models
 type ManagementModelsResponse struct {
 	Count   int            `json:"count"`
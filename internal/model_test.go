@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rtlnl/phoenix/models"
 	"github.com/rtlnl/phoenix/pkg/batch"
@@ -137,6 +138,46 @@ func TestCreateModelFailValidation(t *testing.T) {
 	assert.Equal(t, true, strings.Contains(msg, "Error:Field validation for 'Name' failed on the 'required' tag"))
 }
 
+func TestCreatePopularModel(t *testing.T) {
+	rb, err := json.Marshal(&ManagementModelRequest{Name: "bestsellers", Kind: models.KindPopular})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/management/models/", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "{\"model\":{\"name\":\"bestsellers\",\"signalOrder\":null,\"concatenator\":\"\",\"kind\":\"popular\"},\"message\":\"model created\"}", string(b))
+}
+
+func TestCreateModelMissingSignalOrder(t *testing.T) {
+	r, err := createManagementModelRequest("nosignal", "", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/management/models/", r)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, true, strings.Contains(string(b), "Error:Field validation for 'SignalOrder' failed on the 'required' tag"))
+}
+
 func TestEmptyModel(t *testing.T) {
 	// get client
 	dbc, c := GetTestRedisClient()
@@ -167,6 +208,406 @@ func TestEmptyModel(t *testing.T) {
 	assert.Equal(t, "{\"model\":{\"name\":\"empty\",\"signalOrder\":[\"appleId\"],\"concatenator\":\"\"},\"message\":\"model empty\"}", string(b))
 }
 
+func TestUpdateModel(t *testing.T) {
+	// get client
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// create model
+	m, err := models.NewModel("update", "", []string{"pearId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	if err := dbc.AddOne(m.DataTable(), "123", "[]"); err != nil {
+		t.FailNow()
+	}
+
+	rb, err := json.Marshal(&ManagementModelRequest{Name: "update", SignalOrder: []string{"pearId", "userId"}, Concatenator: "_"})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/models/", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"model\":{\"name\":\"update\",\"signalOrder\":[\"pearId\",\"userId\"],\"concatenator\":\"_\"},\"message\":\"model updated\"}", string(b))
+
+	// the data has been deleted
+	_, err = dbc.GetOne("update", "123")
+	assert.NotNil(t, err)
+}
+
+func TestUpdateModelSameSignalFormat(t *testing.T) {
+	// get client
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// create model
+	m, err := models.NewModel("keep", "", []string{"pearId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	if err := dbc.AddOne(m.DataTable(), "123", "[]"); err != nil {
+		t.FailNow()
+	}
+
+	sortOnUpload := true
+	rb, err := json.Marshal(&ManagementModelRequest{Name: "keep", SignalOrder: []string{"pearId"}, SortOnUpload: &sortOnUpload})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/models/", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"model\":{\"name\":\"keep\",\"signalOrder\":[\"pearId\"],\"concatenator\":\"\",\"sortOnUpload\":true},\"message\":\"model updated\"}", body.String())
+
+	// the data is kept since the signal format did not change
+	_, err = dbc.GetOne("keep", "123")
+	assert.Nil(t, err)
+}
+
+func TestUpdateModelKeepsOmittedSettings(t *testing.T) {
+	// get client
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// create model
+	m, err := models.NewModel("omitted", "", []string{"pearId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+	if err := m.SetItemSchema(models.ItemSchema{RequiredKeys: []string{"item"}}, dbc); err != nil {
+		t.FailNow()
+	}
+	if err := m.SetSortOnUpload(true, dbc); err != nil {
+		t.FailNow()
+	}
+
+	rb, err := json.Marshal(&ManagementModelRequest{Name: "omitted", SignalOrder: []string{"pearId"}})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/models/", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"model\":{\"name\":\"omitted\",\"signalOrder\":[\"pearId\"],\"concatenator\":\"\",\"itemSchema\":{\"requiredKeys\":[\"item\"]},\"sortOnUpload\":true},\"message\":\"model updated\"}", body.String())
+}
+
+func TestUpdateModelKind(t *testing.T) {
+	// get client
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// create model
+	m, err := models.NewModel("kind", "", []string{"pearId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+	if err := dbc.AddOne(m.DataTable(), "123", "[]"); err != nil {
+		t.FailNow()
+	}
+
+	rb, err := json.Marshal(&ManagementModelRequest{Name: "kind", Kind: models.KindPopular})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/models/", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "{\"error\":\"the kind of the model kind cannot be changed\"}", body.String())
+
+	// the data is kept
+	_, err = dbc.GetOne("kind", "123")
+	assert.Nil(t, err)
+}
+
+func TestCreateModelSignalSchema(t *testing.T) {
+	rb, err := json.Marshal(&ManagementModelRequest{
+		Name:         "schema",
+		SignalOrder:  []string{"userId", "device"},
+		Concatenator: "|",
+		SignalSchema: map[string]models.SignalPart{"device": {Type: models.SignalPartEnum, Values: []string{"tv"}}},
+	})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/management/models/", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "{\"model\":{\"name\":\"schema\",\"signalOrder\":[\"userId\",\"device\"],\"concatenator\":\"|\",\"signalSchema\":{\"device\":{\"type\":\"enum\",\"values\":[\"tv\"]}}},\"message\":\"model created\"}", string(b))
+
+	// a wrong schema does not create the model
+	rb, err = json.Marshal(&ManagementModelRequest{
+		Name:         "noschema",
+		SignalOrder:  []string{"userId"},
+		SignalSchema: map[string]models.SignalPart{"device": {Type: models.SignalPartInteger}},
+	})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err = MockRequest(http.MethodPost, "/v1/management/models/", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "{\"error\":\"signal part device is not in the signalOrder [userId]\"}", string(b))
+}
+
+func TestUpdateModelMigrate(t *testing.T) {
+	// get client
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// create model
+	m, err := models.NewModel("migrate", "_", []string{"pearId", "userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	if err := dbc.AddOne(m.DataTable(), "123_456", "[]"); err != nil {
+		t.FailNow()
+	}
+
+	rb, err := json.Marshal(&ManagementModelRequest{Name: "migrate", SignalOrder: []string{"userId", "pearId"}, Concatenator: "#", Migrate: true})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, _, err := MockRequest(http.MethodPut, "/v1/management/models/", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+	assert.Equal(t, http.StatusOK, code)
+
+	_, err = dbc.GetOne("migrate", "456#123")
+	assert.Nil(t, err)
+}
+
+func TestCreateModelFailValidationName(t *testing.T) {
+	r, err := createManagementModelRequest("foo@cache", "", []string{"pearId"})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/management/models/", r)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "{\"error\":\"the name of the model cannot contain the character @\"}", body.String())
+}
+
+func TestUpdateModelFailValidation(t *testing.T) {
+	r, err := createManagementModelRequest("update", "", []string{"pearId", "userId"})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/models/", r)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "{\"error\":\"for two or more signalOrder, a concatenator character from this list is mandatory: ["+strings.Join(concatenatorList, ", ")+"]\"}", string(b))
+}
+
+func TestRollbackModel(t *testing.T) {
+	// get client
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// create model
+	m, err := models.NewModel("rollback", "", []string{"plumId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	ts := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, v := range []string{"first", "second"} {
+		if err := m.AddVersion(models.ModelVersion{BatchID: v, Timestamp: ts, Lines: 2}, dbc); err != nil {
+			t.FailNow()
+		}
+	}
+
+	rb, err := json.Marshal(&ManagementModelVersionRequest{Name: "rollback"})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/models/rollback", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"model\":{\"name\":\"rollback\",\"signalOrder\":[\"plumId\"],\"concatenator\":\"\",\"version\":\"first\",\"versions\":[{\"batchId\":\"first\",\"timestamp\":\"2019-10-01T12:00:00Z\",\"lines\":2,\"errors\":0},{\"batchId\":\"second\",\"timestamp\":\"2019-10-01T12:00:00Z\",\"lines\":2,\"errors\":0}]},\"message\":\"model rolled back\"}", string(b))
+}
+
+func TestGetModelVersions(t *testing.T) {
+	// get client
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// create model
+	m, err := models.NewModel("history", "", []string{"kiwiId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	ts := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	if err := m.AddVersion(models.ModelVersion{BatchID: "first", Timestamp: ts, Lines: 5, Errors: 1}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	code, body, err := MockRequest(http.MethodGet, "/v1/management/models/versions?name=history", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"version\":\"first\",\"versions\":[{\"batchId\":\"first\",\"timestamp\":\"2019-10-01T12:00:00Z\",\"lines\":5,\"errors\":1}],\"message\":\"versions fetched\"}", string(b))
+}
+
+func TestActivateModelVersion(t *testing.T) {
+	// get client
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// create model
+	m, err := models.NewModel("activate", "", []string{"mangoId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	for _, v := range []string{"first", "second"} {
+		if err := m.AddVersion(models.ModelVersion{BatchID: v, Timestamp: time.Now()}, dbc); err != nil {
+			t.FailNow()
+		}
+	}
+
+	rb, err := json.Marshal(&ManagementModelVersionRequest{Name: "activate", Version: "first"})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, _, err := MockRequest(http.MethodPut, "/v1/management/models/versions/activate", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+	assert.Equal(t, http.StatusOK, code)
+
+	stored, err := models.GetModel("activate", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, "first", stored.Version)
+
+	rb, err = json.Marshal(&ManagementModelVersionRequest{Name: "activate", Version: "third"})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPut, "/v1/management/models/versions/activate", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "{\"error\":\"version third not found in model activate\"}", string(b))
+}
+
+func TestDeleteModelVersions(t *testing.T) {
+	// get client
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// create model
+	m, err := models.NewModel("retention", "", []string{"limeId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	ts := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, v := range []string{"first", "second", "third"} {
+		if err := m.AddVersion(models.ModelVersion{BatchID: v, Timestamp: ts}, dbc); err != nil {
+			t.FailNow()
+		}
+	}
+
+	rb, err := json.Marshal(&ManagementModelVersionRequest{Name: "retention", Retention: 1})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodDelete, "/v1/management/models/versions", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"version\":\"third\",\"versions\":[{\"batchId\":\"third\",\"timestamp\":\"2019-10-01T12:00:00Z\",\"lines\":0,\"errors\":0}],\"message\":\"old versions deleted\"}", string(b))
+}
+
 func TestEmptyModelNotExist(t *testing.T) {
 	r, err := createManagementModelRequest("goat", "", []string{"ham"})
 	if err != nil {
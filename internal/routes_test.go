@@ -53,6 +53,7 @@ func tearUp() {
 
 	// subscribe routes here due to multiple tests on the same endpoint
 	// it avoids a panic error for registering the route multiple times
+	router.GET("/v1/streaming", GetStreaming)
 	router.POST("/v1/streaming", CreateStreaming)
 	router.PUT("/v1/streaming", UpdateStreaming)
 	router.DELETE("/v1/streaming", DeleteStreaming)
@@ -60,6 +61,8 @@ func tearUp() {
 
 	router.POST("/v1/batch", Batch)
 	router.GET("/v1/batch/status/:id", BatchStatus)
+	router.POST("/v1/batch/remove-item", BatchRemoveItem)
+	router.GET("/v1/batch/remove-item/status/:id", BatchRemoveItemStatus)
 
 	// Management Routes
 	mg := router.Group("/v1/management")
@@ -68,15 +71,37 @@ func tearUp() {
 	mc := mg.Group("/containers")
 	mc.GET("/", GetContainer)
 	mc.POST("/", CreateContainer)
-	mc.DELETE("/", EmptyContainer)
+	mc.DELETE("/", DeleteContainer)
+	mc.PUT("/empty", EmptyContainer)
+	mc.PUT("/move", MoveContainer)
 	mc.PUT("/link-model", LinkModel)
+	mc.PUT("/unlink-model", UnlinkModel)
+	mc.PUT("/reorder-models", ReorderModels)
+	mc.PUT("/weights", SetWeights)
+	mc.PUT("/model-chain", SetModelChain)
+	mc.PUT("/rules", SetRules)
+	mc.PUT("/blend", SetBlend)
+	mc.GET("/schedules", GetSchedules)
+	mc.POST("/schedules", CreateSchedule)
+	mc.DELETE("/schedules", CancelSchedule)
+
+	// Blocklist routes
+	mb := mg.Group("/blocklist")
+	mb.GET("/", GetBlocklist)
+	mb.POST("/", BlockItems)
+	mb.DELETE("/", UnblockItems)
 
 	// Model routes
 	mm := mg.Group("/models")
 	mm.GET("/", GetModel)
 	mm.POST("/", CreateModel)
+	mm.PUT("/", UpdateModel)
 	mm.DELETE("/", EmptyModel)
 	mm.GET("/preview", GetDataPreview)
+	mm.PUT("/rollback", RollbackModel)
+	mm.GET("/versions", GetModelVersions)
+	mm.PUT("/versions/activate", ActivateModelVersion)
+	mm.DELETE("/versions", DeleteModelVersions)
 }
 
 func tearDown() {
@@ -117,3 +142,19 @@ func MockRequest(method, path string, body io.Reader) (int, *bytes.Buffer, error
 
 	return w.Code, w.Body, nil
 }
+
+// MockRequestWithHeaders works as MockRequest setting the headers of the request and returning the ones of the response
+func MockRequestWithHeaders(method, path string, headers map[string]string, body io.Reader) (int, http.Header, *bytes.Buffer, error) {
+	req, err := http.NewRequest(method, path, body)
+	if err != nil {
+		return -1, nil, nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w.Code, w.Header(), w.Body, nil
+}
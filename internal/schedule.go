@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/db"
+	"github.com/rtlnl/phoenix/utils"
+)
+
+// ManagementScheduleRequest handles the request for scheduling a change of the models linked to a container
+type ManagementScheduleRequest struct {
+	PublicationPoint string    `json:"publicationPoint" binding:"required"`
+	Campaign         string    `json:"campaign" binding:"required"`
+	Models           []string  `json:"models" description:"models linked to the container once the change is applied. Linked models not in the list are unlinked"`
+	ApplyAt          time.Time `json:"applyAt" binding:"required" description:"time from which the change is applied, i.e. 2020-12-24T00:00:00Z"`
+}
+
+// ManagementScheduleCancelRequest handles the request for cancelling a scheduled change
+type ManagementScheduleCancelRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+// ManagementScheduleResponse handles the response object to the client
+type ManagementScheduleResponse struct {
+	Schedule models.ScheduledChange `json:"schedule"`
+	Message  string                 `json:"message"`
+}
+
+// ManagementSchedulesResponse handles the response when there are multiple scheduled changes
+type ManagementSchedulesResponse struct {
+	Count     int                      `json:"count"`
+	Schedules []models.ScheduledChange `json:"schedules"`
+	Message   string                   `json:"message"`
+}
+
+// GetSchedules returns the scheduled changes not yet applied. The publication point and the campaign in the url
+// restrict the changes to the ones of a container
+func GetSchedules(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	// read from params in url
+	pp := c.Query("publicationPoint")
+	cmp := c.Query("campaign")
+
+	schedules, err := models.GetScheduledChanges(pp, cmp, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementSchedulesResponse{
+		Count:     len(schedules),
+		Schedules: schedules,
+		Message:   "schedules fetched",
+	})
+}
+
+// CreateSchedule schedules the change of the models linked to an existing container. The worker applies it
+// once the time is reached
+func CreateSchedule(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var sr ManagementScheduleRequest
+	if err := c.BindJSON(&sr); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	schedule, err := models.NewScheduledChange(sr.PublicationPoint, sr.Campaign, sr.Models, sr.ApplyAt, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	utils.Response(c, http.StatusCreated, &ManagementScheduleResponse{
+		Schedule: schedule,
+		Message:  "change scheduled",
+	})
+}
+
+// CancelSchedule removes a scheduled change that has not been applied yet
+func CancelSchedule(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	var sr ManagementScheduleCancelRequest
+	if err := c.BindJSON(&sr); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	schedule, err := models.CancelScheduledChange(sr.ID, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &ManagementScheduleResponse{
+		Schedule: schedule,
+		Message:  "schedule cancelled",
+	})
+}
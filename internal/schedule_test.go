@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rtlnl/phoenix/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedules(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := models.NewModel("christmas", "", []string{"userId"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewContainer("videos", "holidays", nil, dbc); err != nil {
+		t.FailNow()
+	}
+
+	rb, err := json.Marshal(&ManagementScheduleRequest{
+		PublicationPoint: "videos",
+		Campaign:         "holidays",
+		Models:           []string{"christmas"},
+		ApplyAt:          time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/management/containers/schedules", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	var created ManagementScheduleResponse
+	if err := json.NewDecoder(body).Decode(&created); err != nil {
+		t.FailNow()
+	}
+
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "change scheduled", created.Message)
+	assert.Equal(t, []string{"christmas"}, created.Schedule.Models)
+
+	code, body, err = MockRequest(http.MethodGet, "/v1/management/containers/schedules?publicationPoint=videos&campaign=holidays", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	var listed ManagementSchedulesResponse
+	if err := json.NewDecoder(body).Decode(&listed); err != nil {
+		t.FailNow()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, listed.Count)
+	assert.Equal(t, created.Schedule.ID, listed.Schedules[0].ID)
+
+	rb, err = json.Marshal(&ManagementScheduleCancelRequest{ID: created.Schedule.ID})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, _, err = MockRequest(http.MethodDelete, "/v1/management/containers/schedules", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+
+	// the change is not scheduled anymore
+	code, body, err = MockRequest(http.MethodDelete, "/v1/management/containers/schedules", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "{\"error\":\"scheduled change "+created.Schedule.ID+" not found\"}", string(b))
+}
+
+func TestScheduleInThePast(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := models.NewContainer("videos", "past", nil, dbc); err != nil {
+		t.FailNow()
+	}
+
+	rb, err := json.Marshal(&ManagementScheduleRequest{
+		PublicationPoint: "videos",
+		Campaign:         "past",
+		ApplyAt:          time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/management/containers/schedules", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "{\"error\":\"applyAt 2019-01-01T00:00:00Z must be in the future\"}", string(b))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	jsoniter "github.com/json-iterator/go"
@@ -18,11 +19,17 @@ import (
 // used to fast unmarshal json strings
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
 
+const (
+	// maximum number of attempts for removing an item from a signal changed concurrently
+	maxSwapRetries = 5
+)
+
 // StreamingRequest is the object that represents the payload for the request in the streaming endpoints
 type StreamingRequest struct {
 	SignalID        string             `json:"signalId" binding:"required"`
 	ModelName       string             `json:"modelName" binding:"required"`
 	Recommendations []models.ItemScore `json:"recommendations" binding:"required"`
+	ExpiresAt       *time.Time         `json:"expiresAt" description:"time after which the recommendations are no longer served and deleted. Empty never expires"`
 }
 
 // StreamingResponse is the object that represents the payload for the response in the streaming endpoints
@@ -30,6 +37,48 @@ type StreamingResponse struct {
 	Message string `json:"message"`
 }
 
+// StreamingEntryResponse is the object that represents the payload for the response when fetching a signal
+type StreamingEntryResponse struct {
+	Entry   models.SingleEntry `json:"entry"`
+	Message string             `json:"message"`
+}
+
+// GetStreaming returns the recommendations of a signal. The ETag header can be used with If-Match for updating or
+// deleting the signal only if it did not change in the meantime
+func GetStreaming(c *gin.Context) {
+	dbc := c.MustGet("DB").(db.DB)
+
+	// read from params in url
+	modelName := c.Query("modelName")
+	signalID := c.Query("signalId")
+	if modelName == "" || signalID == "" {
+		utils.ResponseError(c, http.StatusBadRequest, errors.New("missing parameters in url for searching the signal"))
+		return
+	}
+	// get the model
+	m, err := models.GetModel(modelName, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	items, expiresAt, etag, err := m.GetRecommendations(signalID, dbc)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.Header("ETag", etag)
+	utils.Response(c, http.StatusOK, &StreamingEntryResponse{
+		Entry: models.SingleEntry{
+			SignalID:    signalID,
+			Recommended: items,
+			ExpiresAt:   expiresAt,
+		},
+		Message: fmt.Sprintf("signal %s fetched", signalID),
+	})
+}
+
 // CreateStreaming creates a new record in the selected campaign
 func CreateStreaming(c *gin.Context) {
 	dbc := c.MustGet("DB").(db.DB)
@@ -50,18 +99,36 @@ func CreateStreaming(c *gin.Context) {
 		utils.ResponseError(c, http.StatusBadRequest, fmt.Errorf("the expected signal format must be %s", strings.Join(m.SignalOrder, m.Concatenator)))
 		return
 	}
-	// serialize recommendations
-	ser, err := utils.SerializeObject(sr.Recommendations)
-	if err != nil {
-		utils.ResponseError(c, http.StatusInternalServerError, err)
+	if err := m.ValidateSignalParts(sr.SignalID); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := m.ValidateReservedSignal(sr.SignalID); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
 		return
 	}
-	// store data in the database
-	if err := dbc.AddOne(sr.ModelName, sr.SignalID, ser); err != nil {
-		utils.ResponseError(c, http.StatusInternalServerError, err)
+	if err := m.ItemSchema.Validate(sr.Recommendations); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := models.ValidateExpiry(sr.ExpiresAt); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+	// store data in the database. With If-None-Match: * an existing signal is not overwritten
+	var etag string
+	if c.GetHeader("If-None-Match") == models.AnyETag {
+		etag, err = m.SwapRecommendations(sr.SignalID, models.NoETag, sr.Recommendations, sr.ExpiresAt, dbc)
+	} else {
+		etag, err = m.StoreRecommendations(sr.SignalID, sr.Recommendations, sr.ExpiresAt, dbc)
+	}
+	if err != nil {
+		storeError(c, err)
 		return
 	}
 
+	invalidateSignal(m, sr.SignalID, dbc)
+	c.Header("ETag", etag)
 	utils.Response(c, http.StatusCreated, &StreamingResponse{
 		Message: fmt.Sprintf("signal %s created", sr.SignalID),
 	})
@@ -87,18 +154,37 @@ func UpdateStreaming(c *gin.Context) {
 		utils.ResponseError(c, http.StatusBadRequest, fmt.Errorf("the expected signal format must be %s", strings.Join(m.SignalOrder, m.Concatenator)))
 		return
 	}
-	// serialize recommendations
-	ser, err := utils.SerializeObject(sr.Recommendations)
-	if err != nil {
-		utils.ResponseError(c, http.StatusInternalServerError, err)
+	if err := m.ValidateSignalParts(sr.SignalID); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
 		return
 	}
-	// The AddOne method does an UPSERT
-	if err := dbc.AddOne(sr.ModelName, sr.SignalID, ser); err != nil {
-		utils.ResponseError(c, http.StatusInternalServerError, err)
+	if err := m.ValidateReservedSignal(sr.SignalID); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
 		return
-
 	}
+	if err := m.ItemSchema.Validate(sr.Recommendations); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := models.ValidateExpiry(sr.ExpiresAt); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+	// The StoreRecommendations method does an UPSERT. With If-Match the signal is updated only if it did not
+	// change since the client read it
+	var etag string
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		etag, err = m.SwapRecommendations(sr.SignalID, ifMatch, sr.Recommendations, sr.ExpiresAt, dbc)
+	} else {
+		etag, err = m.StoreRecommendations(sr.SignalID, sr.Recommendations, sr.ExpiresAt, dbc)
+	}
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+
+	invalidateSignal(m, sr.SignalID, dbc)
+	c.Header("ETag", etag)
 	utils.Response(c, http.StatusOK, &StreamingResponse{
 		Message: fmt.Sprintf("signal %s updated", sr.SignalID),
 	})
@@ -114,16 +200,22 @@ func DeleteStreaming(c *gin.Context) {
 		return
 	}
 	// get model
-	exists := models.ModelExists(sr.ModelName, dbc)
-	if !exists {
+	m, err := models.GetModel(sr.ModelName, dbc)
+	if err != nil {
 		utils.ResponseError(c, http.StatusNotFound, fmt.Errorf("model %s not found", sr.ModelName))
 		return
 	}
-	// delete record
-	if err := dbc.DeleteOne(sr.ModelName, sr.SignalID); err != nil {
+	// delete record. With If-Match the signal is deleted only if it did not change since the client read it
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		if err := m.DeleteRecommendations(sr.SignalID, ifMatch, dbc); err != nil {
+			storeError(c, err)
+			return
+		}
+	} else if err := dbc.DeleteOne(m.DataTable(), sr.SignalID); err != nil {
 		utils.ResponseError(c, http.StatusNotFound, err)
 		return
 	}
+	invalidateSignal(m, sr.SignalID, dbc)
 
 	utils.Response(c, http.StatusOK, &StreamingResponse{
 		Message: fmt.Sprintf("signal %s deleted", sr.SignalID),
@@ -161,43 +253,50 @@ func DeleteRecommendation(c *gin.Context) {
 		utils.ResponseError(c, http.StatusBadRequest, fmt.Errorf("the expected signal format must be %s", strings.Join(m.SignalOrder, m.Concatenator)))
 		return
 	}
-
-	// get the recommended values
-	rec, err := dbc.GetOne(lr.ModelName, lr.SignalID)
-	if err != nil {
-		utils.ResponseError(c, http.StatusNotFound, err)
-		return
-	}
-
-	// convert the escaped json string to itemscore object
-	var items []models.ItemScore
-	if err := json.Unmarshal([]byte(rec), &items); err != nil {
-		utils.ResponseError(c, http.StatusInternalServerError, err)
+	if err := m.ValidateSignalParts(lr.SignalID); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	// remove the item from the recommendation list
-	// only do so if it actually exists
-	var valid bool
-	items, valid = removeItem(lr.Recommendation, items)
-	if !valid {
-		utils.ResponseError(c, http.StatusBadRequest, errors.New("recommendation does not exist"))
-		return
-	}
+	// the item is removed only if the signal did not change in the meantime. Concurrent changes are retried
+	// unless the client expects a specific version of the signal with If-Match
+	ifMatch := c.GetHeader("If-Match")
+	var etag string
+	for i := 0; ; i++ {
+		// get the recommended values
+		items, expiresAt, current, err := m.GetRecommendations(lr.SignalID, dbc)
+		if err != nil {
+			utils.ResponseError(c, http.StatusNotFound, err)
+			return
+		}
+		if ifMatch != "" && !models.MatchETag(ifMatch, current) {
+			utils.ResponseError(c, http.StatusPreconditionFailed, fmt.Errorf("signal %s does not match the ETag %s", lr.SignalID, ifMatch))
+			return
+		}
 
-	// serialize recommendations
-	ser, err := utils.SerializeObject(items)
-	if err != nil {
-		utils.ResponseError(c, http.StatusInternalServerError, err)
-		return
-	}
+		// remove the item from the recommendation list
+		// only do so if it actually exists
+		var valid bool
+		items, valid = removeItem(lr.Recommendation, items)
+		if !valid {
+			utils.ResponseError(c, http.StatusBadRequest, errors.New("recommendation does not exist"))
+			return
+		}
 
-	// UPSERT the new recommendation list to the DB
-	if err := dbc.AddOne(lr.ModelName, lr.SignalID, ser); err != nil {
-		utils.ResponseError(c, http.StatusInternalServerError, err)
+		// UPSERT the new recommendation list to the DB keeping its expiry
+		etag, err = m.SwapRecommendations(lr.SignalID, current, items, expiresAt, dbc)
+		if err == nil {
+			break
+		}
+		if _, ok := err.(models.PreconditionError); ok && ifMatch == "" && i < maxSwapRetries {
+			continue
+		}
+		storeError(c, err)
 		return
 	}
+	invalidateSignal(m, lr.SignalID, dbc)
 
+	c.Header("ETag", etag)
 	log.Info().Str("DELETE", fmt.Sprintf("SignalId %s", lr.SignalID)).Str("MODEL", fmt.Sprintf("name %s", lr.ModelName))
 
 	utils.Response(c, http.StatusCreated, &StreamingResponse{
@@ -205,6 +304,23 @@ func DeleteRecommendation(c *gin.Context) {
 	})
 }
 
+// invalidateSignal notifies the public instances that the cached recommendations of the signal are stale. Since
+// the signal is already changed, a failure is only logged and the caches are refreshed once their entries expire
+func invalidateSignal(m models.Model, signalID string, dbc db.DB) {
+	if err := models.InvalidateSignal(m.Name, signalID, dbc); err != nil {
+		log.Error().Str("MODEL", m.Name).Str("SIGNAL", signalID).Msgf("could not invalidate the cache. error: %s", err.Error())
+	}
+}
+
+// storeError responds with 412 when the stored recommendations do not match the entity tag of the request
+func storeError(c *gin.Context, err error) {
+	if _, ok := err.(models.PreconditionError); ok {
+		utils.ResponseError(c, http.StatusPreconditionFailed, err)
+		return
+	}
+	utils.ResponseError(c, http.StatusInternalServerError, err)
+}
+
 // Removes a given itemscore item from the itemscore array
 // and returns an array with the item removed and a boolean if the removal
 // was succesful. True if the item was found and removed, false if it was not found.
@@ -171,6 +171,187 @@ func TestStreamingBadSignal(t *testing.T) {
 	assert.Equal(t, "{\"error\":\"the expected signal format must be articleId_userId\"}", string(b))
 }
 
+func TestStreamingBadSignalPart(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	m, err := models.NewModel("typed", "_", []string{"articleId", "userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	if err := m.SetSignalSchema(map[string]models.SignalPart{"articleId": {Type: models.SignalPartInteger}}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	rb, err := createStreamingRequest("typed", "abc_100", []models.ItemScore{{"item": "111", "score": "0.6"}})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/streaming", rb)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "{\"error\":\"signal part articleId must be an integer, got abc\"}", string(b))
+}
+
+func TestStreamingBadItems(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	m, err := models.NewModel("strict", "", []string{"userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	if err := m.SetItemSchema(models.ItemSchema{RequiredKeys: []string{"item", "score"}, NumericScore: true}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	rb, err := createStreamingRequest("strict", "100", []models.ItemScore{{"item": "111", "score": "high"}, {"score": "0.4"}})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/streaming", rb)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "{\"error\":\"item 1 has score high that is not a number; item 2 is missing the key item\"}", string(b))
+}
+
+func TestStreamingExpired(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := models.NewModel("expired", "", []string{"userId"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	expiresAt := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	rb, err := json.Marshal(&StreamingRequest{
+		SignalID:        "100",
+		ModelName:       "expired",
+		Recommendations: []models.ItemScore{{"item": "111", "score": "0.6"}},
+		ExpiresAt:       &expiresAt,
+	})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/streaming", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "{\"error\":\"expiresAt 2019-01-01T00:00:00Z must be in the future\"}", string(b))
+}
+
+func TestStreamingETag(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := models.NewModel("concurrent", "", []string{"userId"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	rb, err := createStreamingRequest("concurrent", "100", []models.ItemScore{{"item": "111"}, {"item": "222"}})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, header, _, err := MockRequestWithHeaders(http.MethodPost, "/v1/streaming", map[string]string{"If-None-Match": "*"}, rb)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusCreated, code)
+	etag := header.Get("ETag")
+
+	// the signal exists already
+	rb, err = createStreamingRequest("concurrent", "100", []models.ItemScore{{"item": "333"}})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, _, body, err := MockRequestWithHeaders(http.MethodPost, "/v1/streaming", map[string]string{"If-None-Match": "*"}, rb)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+	assert.Equal(t, "{\"error\":\"signal 100 already exists\"}", string(b))
+
+	code, header, _, err = MockRequestWithHeaders(http.MethodGet, "/v1/streaming?modelName=concurrent&signalId=100", nil, nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, etag, header.Get("ETag"))
+
+	// another producer removes an item
+	rr, err := createRecommendationRequest("concurrent", "100", models.ItemScore{"item": "111"})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, header, _, err = MockRequestWithHeaders(http.MethodDelete, "/v1/streaming/recommendation", map[string]string{"If-Match": etag}, rr)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusCreated, code)
+	assert.NotEqual(t, etag, header.Get("ETag"))
+
+	// the update is based on the recommendations before the removal
+	rb, err = createStreamingRequest("concurrent", "100", []models.ItemScore{{"item": "333"}})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, _, body, err = MockRequestWithHeaders(http.MethodPut, "/v1/streaming", map[string]string{"If-Match": etag}, rb)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusPreconditionFailed, code)
+	assert.Equal(t, "{\"error\":\"signal 100 does not match the ETag "+strings.ReplaceAll(etag, `"`, `\"`)+"\"}", string(b))
+
+	val, err := dbc.GetOne("concurrent", "100")
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"item":"222"}]`, val)
+}
+
 func TestStreamingBadPayload(t *testing.T) {
 	signal := ""
 	recommendationItems := []models.ItemScore{}
@@ -525,6 +706,50 @@ func TestBatchUploadDirectWithErrors(t *testing.T) {
 	assert.Equal(t, "{\"numberoflines\":\"2\",\"ErrorRecords\":{\"numberoflinesfailed\":\"2\",\"error\":[{\"1\":\"wrong format, the expected signal format must be articleId_userId\"},{\"2\":\"wrong format, the expected signal format must be articleId_userId\"}]}}", string(b))
 }
 
+func TestBatchUploadDirectPopular(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := models.NewPopularModel("charts", dbc); err != nil {
+		t.FailNow()
+	}
+
+	bd := make([]batch.Data, 1)
+	d := []models.ItemScore{
+		{
+			"item":  "111",
+			"score": "0.6",
+		},
+	}
+	bd[0] = map[string][]models.ItemScore{
+		models.PopularSignalID: d,
+	}
+
+	rb, err := createBatchRequestDirect("charts", bd)
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/batch", rb)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "{\"numberoflines\":\"1\",\"ErrorRecords\":{\"numberoflinesfailed\":\"0\",\"error\":null}}", string(b))
+
+	popular, err := dbc.GetOne("charts", models.PopularSignalID)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, "[{\"item\":\"111\",\"score\":\"0.6\"}]", popular)
+}
+
 func TestBatchUploadDirectNoErrors(t *testing.T) {
 	dbc, c := GetTestRedisClient()
 	defer c()
@@ -973,3 +1198,56 @@ func TestStreamingDeleteNonExistingRecommendation(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, code)
 	assert.Equal(t, "{\"error\":\"recommendation does not exist\"}", string(b))
 }
+
+func TestBatchRemoveItem(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := models.NewModel("removeItem", "", []string{"articleId"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	rb, err := json.Marshal(&BatchRemoveItemRequest{ModelName: "removeItem", ItemID: "123"})
+	if err != nil {
+		t.Fail()
+	}
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/batch/remove-item", bytes.NewReader(rb))
+	if err != nil {
+		t.Fail()
+	}
+	assert.Equal(t, http.StatusCreated, code)
+
+	var brs BatchBulkResponse
+	if err := json.Unmarshal(body.Bytes(), &brs); err != nil {
+		t.FailNow()
+	}
+
+	code, body, err = MockRequest(http.MethodGet, "/v1/batch/remove-item/status/"+brs.BatchID, nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"status\":\"QUEUED\",\"modelName\":\"removeItem\",\"itemId\":\"123\",\"scanned\":0,\"updated\":0}", string(b))
+}
+
+func TestBatchRemoveItemStatusNotExist(t *testing.T) {
+	code, body, err := MockRequest(http.MethodGet, "/v1/batch/remove-item/status/missing", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "{\"error\":\"removal job with ID missing not found\"}", string(b))
+}
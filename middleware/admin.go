@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rtlnl/phoenix/utils"
+)
+
+// AdminToken is the middleware that protects the admin routes with a bearer token. When the token is empty
+// the admin routes are disabled
+func AdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			utils.ResponseError(c, http.StatusForbidden, errors.New("admin routes are disabled"))
+			c.Abort()
+			return
+		}
+		auth := c.GetHeader("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(token)) != 1 {
+			utils.ResponseError(c, http.StatusUnauthorized, errors.New("invalid admin token"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/rtlnl/phoenix/pkg/blocklist"
+)
+
+// Blocklist is a middleware instantiating the global blocklist of items
+func Blocklist(b *blocklist.Blocklist) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("Blocklist", b)
+		c.Next()
+	}
+}
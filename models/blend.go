@@ -0,0 +1,129 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/rtlnl/phoenix/utils"
+)
+
+const (
+	// BlendInterleave alternates the items of the linked models position by position
+	BlendInterleave = "interleave"
+	// BlendWeighted merges the items of the linked models summing their scores multiplied by the model weight
+	BlendWeighted = "weighted"
+	// BlendRoundRobin takes one new item from each linked model in turn, skipping the items already served
+	BlendRoundRobin = "round-robin"
+)
+
+var blendStrategies = []string{BlendInterleave, BlendWeighted, BlendRoundRobin}
+
+// Blend is the strategy used by a container for serving the recommendations of all its linked models at once
+type Blend struct {
+	Strategy string             `json:"strategy" description:"one of interleave, weighted or round-robin"`
+	Weights  map[string]float64 `json:"weights,omitempty" description:"score multiplier per linked model used by the weighted strategy. Missing models have weight 1"`
+}
+
+// Validate checks that the strategy exists and that the weights refer to the models in input
+func (b *Blend) Validate(models []string) error {
+	if !utils.StringInSlice(b.Strategy, blendStrategies) {
+		return fmt.Errorf("blend strategy %s not supported. use one of %v", b.Strategy, blendStrategies)
+	}
+	for m, w := range b.Weights {
+		if !utils.StringInSlice(m, models) {
+			return fmt.Errorf("model with name %s is not linked to the container", m)
+		}
+		if w < 0 {
+			return fmt.Errorf("weight of model %s cannot be negative", m)
+		}
+	}
+	return nil
+}
+
+// Merge blends the items of the models following the strategy. The order of the models in input is used for
+// alternating the items and for breaking ties
+func (b *Blend) Merge(models []string, items map[string][]ItemScore) []ItemScore {
+	switch b.Strategy {
+	case BlendWeighted:
+		return b.weighted(models, items)
+	case BlendRoundRobin:
+		return roundRobin(models, items)
+	default:
+		return interleave(models, items)
+	}
+}
+
+// interleave alternates the items of the models. An item recommended by more models is served more times
+func interleave(models []string, items map[string][]ItemScore) []ItemScore {
+	var merged []ItemScore
+	for pos := 0; ; pos++ {
+		added := false
+		for _, m := range models {
+			if pos < len(items[m]) {
+				merged = append(merged, items[m][pos])
+				added = true
+			}
+		}
+		if !added {
+			return merged
+		}
+	}
+}
+
+// roundRobin takes in turn the next item not yet served from each model
+func roundRobin(models []string, items map[string][]ItemScore) []ItemScore {
+	var merged []ItemScore
+	seen := make(map[string]bool)
+	cursors := make([]int, len(models))
+	for {
+		added := false
+		for i, m := range models {
+			for cursors[i] < len(items[m]) {
+				is := items[m][cursors[i]]
+				cursors[i]++
+				if !seen[is["item"]] {
+					seen[is["item"]] = true
+					merged = append(merged, is)
+					added = true
+					break
+				}
+			}
+		}
+		if !added {
+			return merged
+		}
+	}
+}
+
+// weighted sums the scores of the same item across the models and sorts the items by the merged score. Items
+// without a valid score count as 0
+func (b *Blend) weighted(models []string, items map[string][]ItemScore) []ItemScore {
+	var merged []ItemScore
+	scores := make(map[string]float64)
+	for _, m := range models {
+		w, ok := b.Weights[m]
+		if !ok {
+			w = 1
+		}
+		for _, is := range items[m] {
+			if _, ok := scores[is["item"]]; !ok {
+				// copy the item since the score is overwritten
+				cp := make(ItemScore, len(is))
+				for k, v := range is {
+					cp[k] = v
+				}
+				merged = append(merged, cp)
+			}
+			s, _ := is.Score()
+			scores[is["item"]] += s * w
+		}
+	}
+	for _, is := range merged {
+		is["score"] = strconv.FormatFloat(scores[is["item"]], 'f', -1, 64)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return scores[merged[i]["item"]] > scores[merged[j]["item"]]
+	})
+	return merged
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlendMerge(t *testing.T) {
+	models := []string{"a", "b"}
+	items := map[string][]ItemScore{
+		"a": {
+			{"item": "1", "score": "0.9"},
+			{"item": "2", "score": "0.5"},
+			{"item": "3", "score": "0.4"},
+		},
+		"b": {
+			{"item": "2", "score": "0.8"},
+			{"item": "4", "score": "0.7"},
+		},
+	}
+
+	tests := map[string]struct {
+		blend    *Blend
+		expected []ItemScore
+	}{
+		"interleave": {
+			blend: &Blend{Strategy: BlendInterleave},
+			expected: []ItemScore{
+				{"item": "1", "score": "0.9"},
+				{"item": "2", "score": "0.8"},
+				{"item": "2", "score": "0.5"},
+				{"item": "4", "score": "0.7"},
+				{"item": "3", "score": "0.4"},
+			},
+		},
+		"round robin": {
+			blend: &Blend{Strategy: BlendRoundRobin},
+			expected: []ItemScore{
+				{"item": "1", "score": "0.9"},
+				{"item": "2", "score": "0.8"},
+				{"item": "3", "score": "0.4"},
+				{"item": "4", "score": "0.7"},
+			},
+		},
+		"weighted": {
+			blend: &Blend{Strategy: BlendWeighted, Weights: map[string]float64{"a": 0.5}},
+			expected: []ItemScore{
+				{"item": "2", "score": "1.05"},
+				{"item": "4", "score": "0.7"},
+				{"item": "1", "score": "0.45"},
+				{"item": "3", "score": "0.2"},
+			},
+		},
+	}
+	for testName, test := range tests {
+		t.Logf("Running test case %s", testName)
+		assert.Equal(t, test.expected, test.blend.Merge(models, items))
+	}
+
+	// the items in input are not modified
+	assert.Equal(t, "0.9", items["a"][0]["score"])
+}
+
+func TestSetBlend(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	ma, err := NewModel("blenda", "", []string{"userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer ma.DeleteModel(dbc)
+	mb, err := NewModel("blendb", "", []string{"userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer mb.DeleteModel(dbc)
+
+	container, err := NewContainer("blend", "campaign", []string{"blenda", "blendb"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer container.DeleteContainer(dbc)
+
+	err = container.SetBlend(Blend{Strategy: "random"}, dbc)
+	assert.Equal(t, "blend strategy random not supported. use one of [interleave weighted round-robin]", err.Error())
+
+	err = container.SetBlend(Blend{Strategy: BlendWeighted, Weights: map[string]float64{"other": 1}}, dbc)
+	assert.Equal(t, "model with name other is not linked to the container", err.Error())
+
+	if err := container.SetBlend(Blend{Strategy: BlendWeighted, Weights: map[string]float64{"blenda": 2}}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err := GetContainer("blend", "campaign", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, &Blend{Strategy: BlendWeighted, Weights: map[string]float64{"blenda": 2}}, stored.Blend)
+
+	// deleting a model removes its weight
+	mc, err := NewModel("blendc", "", []string{"userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	if err := stored.LinkModel([]string{"blendc"}, dbc); err != nil {
+		t.FailNow()
+	}
+	if err := stored.SetBlend(Blend{Strategy: BlendWeighted, Weights: map[string]float64{"blenda": 2, "blendc": 3}}, dbc); err != nil {
+		t.FailNow()
+	}
+	if err := mc.DeleteModel(dbc); err != nil {
+		t.FailNow()
+	}
+	stored, err = GetContainer("blend", "campaign", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, map[string]float64{"blenda": 2}, stored.Blend.Weights)
+
+	// unlinking a model removes its weight
+	if err := stored.UnlinkModel([]string{"blenda"}, dbc); err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, 0, len(stored.Blend.Weights))
+
+	// an empty strategy removes the blending
+	if err := stored.SetBlend(Blend{}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err = GetContainer("blend", "campaign", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Nil(t, stored.Blend)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rtlnl/phoenix/pkg/db"
+)
+
+const (
+	tableBlocklist = "blocklist"
+	// BlocklistChannel is the channel where the changes of the blocklist are published
+	BlocklistChannel = "blocklist"
+)
+
+// BlockItems adds the items to the global blocklist and notifies the subscribers of the change. The subscribers
+// are notified of the items already blocked even when blocking one of them fails
+func BlockItems(items []string, dbc db.DB) error {
+	ts := time.Now().UTC().Format(time.RFC3339)
+	for i, item := range items {
+		// store when the item has been blocked
+		if err := dbc.AddOne(tableBlocklist, item, ts); err != nil {
+			return publishBlocklist(i, "blocked", fmt.Errorf("error in blocking item %s. error: %s", item, err.Error()), dbc)
+		}
+	}
+	return publishBlocklist(len(items), "blocked", nil, dbc)
+}
+
+// UnblockItems removes the items from the global blocklist and notifies the subscribers of the change. Nothing
+// is removed when one of the items is not blocked
+func UnblockItems(items []string, dbc db.DB) error {
+	for _, item := range items {
+		if _, err := dbc.GetOne(tableBlocklist, item); err != nil {
+			return fmt.Errorf("item %s is not blocked", item)
+		}
+	}
+	for i, item := range items {
+		if err := dbc.DeleteOne(tableBlocklist, item); err != nil {
+			return publishBlocklist(i, "unblocked", fmt.Errorf("error in unblocking item %s. error: %s", item, err.Error()), dbc)
+		}
+	}
+	return publishBlocklist(len(items), "unblocked", nil, dbc)
+}
+
+// publishBlocklist notifies the subscribers when at least one item changed and returns the error of the change
+// or, when the change succeeded, the one of the notification
+func publishBlocklist(changed int, msg string, err error, dbc db.DB) error {
+	if changed == 0 {
+		return err
+	}
+	if perr := dbc.Publish(BlocklistChannel, msg); err == nil {
+		return perr
+	}
+	return err
+}
+
+// GetBlockedItems returns all the items of the global blocklist sorted by ID
+func GetBlockedItems(dbc db.DB) ([]string, error) {
+	items := []string{}
+	err := dbc.IterateRecords(tableBlocklist, func(item, ts string) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in returning the blocked items from the database. error: %s", err.Error())
+	}
+	sort.Strings(items)
+	return items, nil
+}
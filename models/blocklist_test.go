@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockItems(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	msgs, unsubscribe, err := dbc.Subscribe(BlocklistChannel)
+	if err != nil {
+		t.FailNow()
+	}
+	defer unsubscribe()
+
+	if err := BlockItems([]string{"2", "1", "3"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	// subscribers are notified
+	select {
+	case msg := <-msgs:
+		assert.Equal(t, "blocked", msg)
+	case <-time.After(time.Second):
+		t.Fail()
+	}
+
+	items, err := GetBlockedItems(dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, []string{"1", "2", "3"}, items)
+
+	if err := UnblockItems([]string{"2"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	items, err = GetBlockedItems(dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, []string{"1", "3"}, items)
+
+	err = UnblockItems([]string{"2"}, dbc)
+	assert.Equal(t, "item 2 is not blocked", err.Error())
+
+	// nothing is unblocked when one of the items is not blocked
+	err = UnblockItems([]string{"1", "2"}, dbc)
+	assert.Equal(t, "item 2 is not blocked", err.Error())
+
+	items, err = GetBlockedItems(dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, []string{"1", "3"}, items)
+}
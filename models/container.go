@@ -2,6 +2,9 @@ package models
 
 import (
 	"fmt"
+	"hash/fnv"
+	"sort"
+
 	"github.com/rtlnl/phoenix/pkg/db"
 	"github.com/rtlnl/phoenix/utils"
 )
@@ -14,9 +17,14 @@ const (
 // Container is used simply as reference to understand where the models are connected to
 // Models and Containers are separate entities that have a "fake" relationship
 type Container struct {
-	PublicationPoint string   `json:"publicationPoint" description:"publication point where the model will be connected to"`
-	Campaign         string   `json:"campaign" description:"name for where in a potential place of the internal products, the model will be placed"`
-	Models           []string `json:"models,omitempty" description:"list of models that are linked to this container"`
+	PublicationPoint string         `json:"publicationPoint" description:"publication point where the model will be connected to"`
+	Campaign         string         `json:"campaign" description:"name for where in a potential place of the internal products, the model will be placed"`
+	Models           []string       `json:"models,omitempty" description:"list of models that are linked to this container"`
+	Weights          map[string]int `json:"weights,omitempty" description:"traffic weight per linked model used for splitting the requests between models"`
+	DefaultModel     string         `json:"defaultModel,omitempty" description:"model used when neither the request nor the traffic split select one"`
+	FallbackModels   []string       `json:"fallbackModels,omitempty" description:"ordered list of models used when the selected model has no entry for the signal"`
+	Rules            *Rules         `json:"rules,omitempty" description:"business rules applied to the recommendations before serving them"`
+	Blend            *Blend         `json:"blend,omitempty" description:"strategy used for serving the recommendations of all the linked models at once"`
 }
 
 // NewContainer creates a new container in the database
@@ -76,8 +84,13 @@ func ContainerExists(publicationPoint, campaign string, dbc db.DB) bool {
 	return true
 }
 
-// DeleteContainer deletes the content of the container by truncating the PublicationPoint (aka setName)
+// DeleteContainer deletes the content of the container by truncating the PublicationPoint (aka setName). The
+// scheduled changes of the container are deleted as well, so that they are not applied to a new container with
+// the same name
 func (c *Container) DeleteContainer(dbc db.DB) error {
+	if err := deleteScheduledChanges(c.PublicationPoint, c.Campaign, dbc); err != nil {
+		return err
+	}
 	// delete from the containers table
 	return dbc.DeleteOne(tableContainers, ContainerUniqueName(c.PublicationPoint, c.Campaign))
 }
@@ -88,14 +101,223 @@ func (c *Container) LinkModel(models []string, dbc db.DB) error {
 	tmp := append(c.Models, models...)
 	// update models property
 	c.Models = utils.RemoveEmptyValueInSlice(tmp)
+	return c.save(dbc)
+}
+
+// UnlinkModel removes the models from the container together with their traffic weights. If the default model
+// is unlinked, the container falls back to the first linked model
+func (c *Container) UnlinkModel(models []string, dbc db.DB) error {
+	for _, m := range models {
+		if !ModelExists(m, dbc) {
+			return fmt.Errorf("model with name %s not found", m)
+		}
+		if !utils.StringInSlice(m, c.Models) {
+			return fmt.Errorf("model with name %s is not linked to the container", m)
+		}
+	}
+	for _, m := range models {
+		c.Models = utils.RemoveElemFromSlice(m, c.Models)
+		delete(c.Weights, m)
+		if c.Blend != nil {
+			delete(c.Blend.Weights, m)
+		}
+		if c.DefaultModel == m {
+			c.DefaultModel = ""
+		}
+	}
+	return c.save(dbc)
+}
+
+// SetModels replaces the models linked to the container. The traffic weights, the blend weights and the default
+// model of the models that are no longer linked are removed
+func (c *Container) SetModels(models []string, dbc db.DB) error {
+	for _, m := range models {
+		if !ModelExists(m, dbc) {
+			return fmt.Errorf("model with name %s not found", m)
+		}
+	}
+	for _, m := range c.Models {
+		if utils.StringInSlice(m, models) {
+			continue
+		}
+		delete(c.Weights, m)
+		if c.Blend != nil {
+			delete(c.Blend.Weights, m)
+		}
+		if c.DefaultModel == m {
+			c.DefaultModel = ""
+		}
+	}
+	c.Models = utils.RemoveEmptyValueInSlice(models)
+	return c.save(dbc)
+}
+
+// EmptyContainer unlinks all the models from the container and resets the traffic split, the model chain and the blending
+func (c *Container) EmptyContainer(dbc db.DB) error {
+	c.Models = nil
+	c.Weights = nil
+	c.DefaultModel = ""
+	c.FallbackModels = nil
+	c.Blend = nil
+	return c.save(dbc)
+}
+
+// ReorderModels changes the order of the linked models. The first model is served when the container has no
+// default model. The list in input must contain exactly the models already linked to the container
+func (c *Container) ReorderModels(models []string, dbc db.DB) error {
+	if len(models) != len(c.Models) {
+		return fmt.Errorf("models %v must contain exactly the models linked to the container", models)
+	}
+	// compare the lists counting each name, so that duplicates cannot replace a linked model
+	linked := make(map[string]int, len(c.Models))
+	for _, m := range c.Models {
+		linked[m]++
+	}
+	for _, m := range models {
+		if !ModelExists(m, dbc) {
+			return fmt.Errorf("model with name %s not found", m)
+		}
+		if linked[m] == 0 {
+			return fmt.Errorf("models %v must contain exactly the models linked to the container", models)
+		}
+		linked[m]--
+	}
+	c.Models = models
+	return c.save(dbc)
+}
+
+// Move changes the publication point and the campaign of the container. The changes scheduled for the container
+// follow it. Since the traffic split is based on the container name, the signals might be assigned to different
+// models after moving a container with weights
+func (c *Container) Move(publicationPoint, campaign string, dbc db.DB) error {
+	if ContainerExists(publicationPoint, campaign, dbc) {
+		return fmt.Errorf("container with publication point %s and campaign %s already exists", publicationPoint, campaign)
+	}
+	old := *c
+	c.PublicationPoint = publicationPoint
+	c.Campaign = campaign
+	// store the new container first so that it is never lost
+	if err := c.save(dbc); err != nil {
+		return err
+	}
+	if err := moveScheduledChanges(old.PublicationPoint, old.Campaign, publicationPoint, campaign, dbc); err != nil {
+		return fmt.Errorf("could not move the scheduled changes of the container. error: %s", err.Error())
+	}
+	return old.DeleteContainer(dbc)
+}
+
+// SetWeights updates the traffic weights of the linked models. An empty map disables the traffic splitting
+func (c *Container) SetWeights(weights map[string]int, dbc db.DB) error {
+	for m, w := range weights {
+		if !utils.StringInSlice(m, c.Models) {
+			return fmt.Errorf("model with name %s is not linked to the container", m)
+		}
+		if w < 0 {
+			return fmt.Errorf("weight of model %s cannot be negative", m)
+		}
+	}
+	// update weights property
+	c.Weights = weights
+	return c.save(dbc)
+}
+
+// SetModelChain updates the default model and the ordered list of fallback models of the container
+func (c *Container) SetModelChain(defaultModel string, fallbackModels []string, dbc db.DB) error {
+	if defaultModel != "" && !utils.StringInSlice(defaultModel, c.Models) {
+		return fmt.Errorf("model with name %s is not linked to the container", defaultModel)
+	}
+	for _, m := range fallbackModels {
+		if !ModelExists(m, dbc) {
+			return fmt.Errorf("model with name %s not found", m)
+		}
+	}
+	// update chain properties
+	c.DefaultModel = defaultModel
+	c.FallbackModels = utils.RemoveEmptyValueInSlice(fallbackModels)
+	return c.save(dbc)
+}
+
+// SetRules updates the business rules of the container. Empty rules remove any filtering
+func (c *Container) SetRules(rules Rules, dbc db.DB) error {
+	if err := rules.Validate(); err != nil {
+		return err
+	}
+	// update rules property
+	c.Rules = nil
+	if !rules.IsEmpty() {
+		c.Rules = &rules
+	}
+	return c.save(dbc)
+}
+
+// SetBlend updates the strategy used for blending the linked models. An empty strategy serves one model per request
+func (c *Container) SetBlend(blend Blend, dbc db.DB) error {
+	// update blend property
+	c.Blend = nil
+	if blend.Strategy != "" {
+		if err := blend.Validate(c.Models); err != nil {
+			return err
+		}
+		c.Blend = &blend
+	}
+	return c.save(dbc)
+}
+
+// ModelChain returns the ordered list of models to try for serving a signal, starting from the selected model
+// and followed by the fallback models of the container
+func (c *Container) ModelChain(modelName string) []string {
+	chain := []string{modelName}
+	for _, m := range c.FallbackModels {
+		if !utils.StringInSlice(m, chain) {
+			chain = append(chain, m)
+		}
+	}
+	return chain
+}
+
+// WeightedModel picks the model that serves the signal based on the traffic weights. The signal is hashed
+// together with the container name so that the same signal always lands in the same bucket. The returned
+// bucket starts from 1; 0 means that no traffic splitting is configured for the container
+func (c *Container) WeightedModel(signalID string) (string, int) {
+	// sort the models to have a stable bucket assignment
+	var names []string
+	total := 0
+	for _, m := range c.Models {
+		if w := c.Weights[m]; w > 0 {
+			names = append(names, m)
+			total += w
+		}
+	}
+	if total == 0 {
+		return "", 0
+	}
+	sort.Strings(names)
+
+	h := fnv.New32a()
+	h.Write([]byte(ContainerUniqueName(c.PublicationPoint, c.Campaign)))
+	h.Write([]byte(signalID))
+	bucket := int(h.Sum32()%uint32(total)) + 1
+
+	// walk through the cumulative weights until the bucket is reached
+	cumulative := 0
+	for _, m := range names {
+		cumulative += c.Weights[m]
+		if bucket <= cumulative {
+			return m, bucket
+		}
+	}
+	return "", 0
+}
+
+// save stores the container in the database
+func (c *Container) save(dbc db.DB) error {
 	// serialize object
 	container, err := utils.SerializeObject(c)
 	if err != nil {
 		return fmt.Errorf("failed to serialize container. error: %s", err.Error())
 	}
 	// update database
-	err = dbc.AddOne(tableContainers, ContainerUniqueName(c.PublicationPoint, c.Campaign), container)
-	if err != nil {
+	if err := dbc.AddOne(tableContainers, ContainerUniqueName(c.PublicationPoint, c.Campaign), container); err != nil {
 		return fmt.Errorf("failed to insert container into db. error: %s", err.Error())
 	}
 	return nil
@@ -1,7 +1,9 @@
 package models
 
 import (
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -133,3 +135,245 @@ func TestLinkModel(t *testing.T) {
 
 	assert.Equal(t, 2, len(container.Models))
 }
+
+func TestSetWeights(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := NewModel("variantA", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := NewModel("variantB", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	container, err := NewContainer("abtest", "campaign", []string{"variantA", "variantB"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	err = container.SetWeights(map[string]int{"variantA": 80, "variantC": 20}, dbc)
+	assert.Equal(t, "model with name variantC is not linked to the container", err.Error())
+
+	err = container.SetWeights(map[string]int{"variantA": -1}, dbc)
+	assert.Equal(t, "weight of model variantA cannot be negative", err.Error())
+
+	if err := container.SetWeights(map[string]int{"variantA": 80, "variantB": 20}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err := GetContainer("abtest", "campaign", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	assert.Equal(t, 80, stored.Weights["variantA"])
+	assert.Equal(t, 20, stored.Weights["variantB"])
+}
+
+func TestWeightedModel(t *testing.T) {
+	c := Container{
+		PublicationPoint: "pp",
+		Campaign:         "cmp",
+		Models:           []string{"a", "b", "c"},
+	}
+
+	// no weights means no traffic splitting
+	m, bucket := c.WeightedModel("123")
+	assert.Equal(t, "", m)
+	assert.Equal(t, 0, bucket)
+
+	c.Weights = map[string]int{"a": 50, "b": 50}
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		signal := strconv.Itoa(i)
+		m, bucket := c.WeightedModel(signal)
+
+		// the same signal always lands in the same bucket
+		sameModel, sameBucket := c.WeightedModel(signal)
+		assert.Equal(t, m, sameModel)
+		assert.Equal(t, bucket, sameBucket)
+
+		assert.True(t, bucket >= 1 && bucket <= 100)
+		counts[m]++
+	}
+
+	assert.Equal(t, 0, counts["c"])
+	assert.InDelta(t, 500, counts["a"], 100)
+	assert.InDelta(t, 500, counts["b"], 100)
+}
+
+func TestSetModelChain(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := NewModel("primary", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := NewModel("fallback", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	container, err := NewContainer("chain", "campaign", []string{"primary"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	err = container.SetModelChain("fallback", nil, dbc)
+	assert.Equal(t, "model with name fallback is not linked to the container", err.Error())
+
+	err = container.SetModelChain("primary", []string{"missing"}, dbc)
+	assert.Equal(t, "model with name missing not found", err.Error())
+
+	if err := container.SetModelChain("primary", []string{"fallback"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err := GetContainer("chain", "campaign", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	assert.Equal(t, "primary", stored.DefaultModel)
+	assert.Equal(t, []string{"fallback"}, stored.FallbackModels)
+	assert.Equal(t, []string{"primary", "fallback"}, stored.ModelChain("primary"))
+	assert.Equal(t, []string{"fallback"}, stored.ModelChain("fallback"))
+}
+
+func TestUnlinkModel(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	for _, m := range []string{"first", "second"} {
+		if _, err := NewModel(m, "", []string{"signal"}, dbc); err != nil {
+			t.FailNow()
+		}
+	}
+
+	container, err := NewContainer("unlink", "campaign", []string{"first", "second"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	if err := container.SetWeights(map[string]int{"first": 50, "second": 50}, dbc); err != nil {
+		t.FailNow()
+	}
+	if err := container.SetModelChain("first", nil, dbc); err != nil {
+		t.FailNow()
+	}
+
+	err = container.UnlinkModel([]string{"missing"}, dbc)
+	assert.Equal(t, "model with name missing not found", err.Error())
+
+	if err := container.UnlinkModel([]string{"first"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err := GetContainer("unlink", "campaign", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, []string{"second"}, stored.Models)
+	assert.Equal(t, map[string]int{"second": 50}, stored.Weights)
+	assert.Equal(t, "", stored.DefaultModel)
+
+	err = stored.UnlinkModel([]string{"first"}, dbc)
+	assert.Equal(t, "model with name first is not linked to the container", err.Error())
+}
+
+func TestEmptyContainer(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := NewModel("emptied", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	container, err := NewContainer("empty", "campaign", []string{"emptied"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	if err := container.EmptyContainer(dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err := GetContainer("empty", "campaign", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, 0, len(stored.Models))
+}
+
+func TestReorderModels(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	for _, m := range []string{"one", "two"} {
+		if _, err := NewModel(m, "", []string{"signal"}, dbc); err != nil {
+			t.FailNow()
+		}
+	}
+
+	container, err := NewContainer("reorder", "campaign", []string{"one", "two"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	err = container.ReorderModels([]string{"two"}, dbc)
+	assert.Equal(t, "models [two] must contain exactly the models linked to the container", err.Error())
+
+	// a duplicate cannot replace a linked model
+	err = container.ReorderModels([]string{"one", "one"}, dbc)
+	assert.Equal(t, "models [one one] must contain exactly the models linked to the container", err.Error())
+
+	if err := container.ReorderModels([]string{"two", "one"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err := GetContainer("reorder", "campaign", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, []string{"two", "one"}, stored.Models)
+}
+
+func TestMoveContainer(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	if _, err := NewContainer("taken", "campaign", nil, dbc); err != nil {
+		t.FailNow()
+	}
+
+	container, err := NewContainer("move", "campaign", nil, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	err = container.Move("taken", "campaign", dbc)
+	assert.Equal(t, "container with publication point taken and campaign campaign already exists", err.Error())
+
+	// the pending changes follow the container
+	sc, err := NewScheduledChange("move", "campaign", nil, time.Now().Add(time.Hour), dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer CancelScheduledChange(sc.ID, dbc)
+
+	if err := container.Move("moved", "other", dbc); err != nil {
+		t.FailNow()
+	}
+
+	assert.Equal(t, false, ContainerExists("move", "campaign", dbc))
+	assert.Equal(t, true, ContainerExists("moved", "other", dbc))
+
+	changes, err := GetScheduledChanges("moved", "other", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, sc.ID, changes[0].ID)
+	}
+}
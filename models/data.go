@@ -1,12 +1,23 @@
 package models
 
 import (
+	"time"
+
 	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// PopularSignalID is the reserved signal under which the popular models store their global list of items
+	PopularSignalID = "_popular"
+	// separates the name of a model from the suffix of its internal tables
+	tableSeparator = "@"
+)
+
 var (
-	reservedNames = []string{"models", "containers"}
+	reservedNames = []string{tableModels, tableContainers, tableBlocklist, tableSchedules}
+	// signals that cannot be used by the personalized models
+	reservedSignals = []string{PopularSignalID}
 	// used to fast unmarshal json strings
 	json = jsoniter.ConfigCompatibleWithStandardLibrary
 )
@@ -30,6 +41,7 @@ type RecordQueue struct {
 type SingleEntry struct {
 	SignalID    string      `json:"signalId"`
 	Recommended []ItemScore `json:"recommended"`
+	ExpiresAt   *time.Time  `json:"expiresAt,omitempty"`
 }
 
 // DeserializeItemScoreArray attempts to convert a string into an array of ItemScore
@@ -56,8 +68,7 @@ func DeserializeLineErrorArray(s string) ([]LineError, error) {
 func DeserializeSingleEntryArray(preview map[string]string) ([]SingleEntry, error) {
 	var seArr []SingleEntry
 	for signalID, vals := range preview {
-		var is []ItemScore
-		err := json.UnmarshalFromString(vals, &is)
+		is, expiresAt, err := DeserializeEntry(vals)
 		if err != nil {
 			log.Error().Msgf("could not deserialize value. error: %s", err.Error())
 			continue
@@ -65,6 +76,7 @@ func DeserializeSingleEntryArray(preview map[string]string) ([]SingleEntry, erro
 		seArr = append(seArr, SingleEntry{
 			SignalID:    signalID,
 			Recommended: is,
+			ExpiresAt:   expiresAt,
 		})
 	}
 	return seArr, nil
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rtlnl/phoenix/pkg/db"
+)
+
+const (
+	// AnyETag matches any stored recommendations of the signal
+	AnyETag = "*"
+	// NoETag matches only a signal without recommendations
+	NoETag = ""
+)
+
+// PreconditionError is returned when the stored recommendations of the signal do not match the entity tag
+// of the request because they changed in the meantime
+type PreconditionError struct {
+	error
+}
+
+// ETag returns the entity tag of the recommendations of a signal as stored in the database
+func ETag(value string) string {
+	return fmt.Sprintf(`"%s"`, db.Checksum(value))
+}
+
+// MatchETag checks if the entity tag of the stored recommendations matches the one of the request. Weak and
+// strong entity tags are compared in the same way
+func MatchETag(requested, etag string) bool {
+	return requested == AnyETag || checksum(requested) == checksum(etag)
+}
+
+// GetRecommendations returns the recommendations of the signal in the data currently served by the model together
+// with their expiry and entity tag
+func (m *Model) GetRecommendations(signalID string, dbc db.DB) ([]ItemScore, *time.Time, string, error) {
+	v, err := dbc.GetOne(m.DataTable(), signalID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	items, expiresAt, err := DeserializeEntry(v)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("could not deserialize recommendations. error: %s", err.Error())
+	}
+	return items, expiresAt, ETag(v), nil
+}
+
+// SwapRecommendations stores the recommendations of the signal only if the stored ones match the entity tag.
+// AnyETag matches any stored recommendations while NoETag matches only a signal without recommendations. It
+// returns the entity tag of the new recommendations
+func (m *Model) SwapRecommendations(signalID, etag string, items []ItemScore, expiresAt *time.Time, dbc db.DB) (string, error) {
+	ser, err := SerializeEntry(items, expiresAt)
+	if err != nil {
+		return "", fmt.Errorf("could not serialize recommendations. error: %s", err.Error())
+	}
+	ok, err := dbc.CompareAndSwap(m.DataTable(), signalID, checksum(etag), ser, expiresAt)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		if etag == NoETag {
+			return "", PreconditionError{fmt.Errorf("signal %s already exists", signalID)}
+		}
+		return "", PreconditionError{fmt.Errorf("signal %s does not match the ETag %s", signalID, etag)}
+	}
+	return ETag(ser), nil
+}
+
+// DeleteRecommendations deletes the recommendations of the signal only if they match the entity tag. AnyETag
+// matches any stored recommendations
+func (m *Model) DeleteRecommendations(signalID, etag string, dbc db.DB) error {
+	ok, err := dbc.CompareAndDelete(m.DataTable(), signalID, checksum(etag))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return PreconditionError{fmt.Errorf("signal %s does not match the ETag %s", signalID, etag)}
+	}
+	return nil
+}
+
+// checksum converts the entity tag in the checksum of the value stored in the database
+func checksum(etag string) string {
+	switch etag {
+	case AnyETag:
+		return db.AnyChecksum
+	case NoETag:
+		return db.NoChecksum
+	}
+	return strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchETag(t *testing.T) {
+	etag := ETag(`[{"item":"1"}]`)
+
+	assert.True(t, MatchETag(etag, etag))
+	assert.True(t, MatchETag("W/"+etag, etag))
+	assert.True(t, MatchETag(AnyETag, etag))
+	assert.False(t, MatchETag(ETag(`[]`), etag))
+}
+
+func TestSwapRecommendations(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	m, err := NewModel("swap", "", []string{"userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+
+	etag, err := m.SwapRecommendations("1", NoETag, []ItemScore{{"item": "a"}}, nil, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	_, err = m.SwapRecommendations("1", NoETag, []ItemScore{{"item": "b"}}, nil, dbc)
+	assert.IsType(t, PreconditionError{}, err)
+	assert.Equal(t, "signal 1 already exists", err.Error())
+
+	updated, err := m.SwapRecommendations("1", etag, []ItemScore{{"item": "b"}}, nil, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	// the recommendations changed since the first entity tag was read
+	_, err = m.SwapRecommendations("1", etag, []ItemScore{{"item": "c"}}, nil, dbc)
+	assert.IsType(t, PreconditionError{}, err)
+
+	items, _, current, err := m.GetRecommendations("1", dbc)
+	assert.Nil(t, err)
+	assert.Equal(t, []ItemScore{{"item": "b"}}, items)
+	assert.Equal(t, updated, current)
+
+	err = m.DeleteRecommendations("1", etag, dbc)
+	assert.IsType(t, PreconditionError{}, err)
+
+	assert.Nil(t, m.DeleteRecommendations("1", updated, dbc))
+}
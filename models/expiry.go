@@ -0,0 +1,147 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rtlnl/phoenix/pkg/db"
+	"github.com/rtlnl/phoenix/utils"
+)
+
+const (
+	// maximum number of expired signals deleted at once from a table
+	maxExpiredDeletion = 1000
+)
+
+// expiringEntry is the format of the recommendations of a signal stored with an expiry. The recommendations
+// without expiry are stored as a plain list of items
+type expiringEntry struct {
+	ExpiresAt   time.Time   `json:"expiresAt"`
+	Recommended []ItemScore `json:"recommended"`
+}
+
+// SerializeEntry converts the recommendations of a signal in the string stored in the database. The expiry is
+// stored only when set
+func SerializeEntry(items []ItemScore, expiresAt *time.Time) (string, error) {
+	if expiresAt == nil {
+		return utils.SerializeObject(items)
+	}
+	return utils.SerializeObject(expiringEntry{ExpiresAt: *expiresAt, Recommended: items})
+}
+
+// DeserializeEntry attempts to convert the string stored in the database in the recommendations of a signal
+// together with their expiry. The expiry is nil for the recommendations that never expire
+func DeserializeEntry(s string) ([]ItemScore, *time.Time, error) {
+	if !isExpiringEntry(s) {
+		items, err := DeserializeItemScoreArray(s)
+		return items, nil, err
+	}
+	var e expiringEntry
+	if err := json.UnmarshalFromString(s, &e); err != nil {
+		return nil, nil, err
+	}
+	return e.Recommended, &e.ExpiresAt, nil
+}
+
+// EntryExpiry returns the expiry of the string stored in the database without deserializing the recommendations
+func EntryExpiry(s string) *time.Time {
+	if !isExpiringEntry(s) {
+		return nil
+	}
+	e := struct {
+		ExpiresAt time.Time `json:"expiresAt"`
+	}{}
+	if err := json.UnmarshalFromString(s, &e); err != nil {
+		return nil
+	}
+	return &e.ExpiresAt
+}
+
+// IsExpired checks if the expiry is in the past. A nil expiry never expires
+func IsExpired(expiresAt *time.Time) bool {
+	return expiresAt != nil && !expiresAt.After(time.Now())
+}
+
+// ValidateExpiry checks that the recommendations are not stored already expired
+func ValidateExpiry(expiresAt *time.Time) error {
+	if IsExpired(expiresAt) {
+		return fmt.Errorf("expiresAt %s must be in the future", expiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// StoreRecommendations stores the recommendations of the signal in the data currently served by the model. When
+// the expiry is set the recommendations stop being served after it and they are deleted by DeleteExpiredSignals.
+// It returns the entity tag of the stored recommendations
+func (m *Model) StoreRecommendations(signalID string, items []ItemScore, expiresAt *time.Time, dbc db.DB) (string, error) {
+	ser, err := SerializeEntry(items, expiresAt)
+	if err != nil {
+		return "", fmt.Errorf("could not serialize recommendations. error: %s", err.Error())
+	}
+	// the previous expiry of the signal must not delete the new recommendations
+	if err := dbc.AddOneWithExpiry(m.DataTable(), signalID, ser, expiresAt); err != nil {
+		return "", err
+	}
+	return ETag(ser), nil
+}
+
+// DeleteExpiredSignals deletes the expired recommendations from all the versions of the model and from the shared
+// cache. It returns the number of signals deleted, the entries of the shared cache are not counted
+func (m *Model) DeleteExpiredSignals(now time.Time, dbc db.DB) (int, error) {
+	tables := []string{m.DataTable()}
+	for _, v := range m.Versions {
+		if t := m.VersionTable(v.BatchID); !utils.StringInSlice(t, tables) {
+			tables = append(tables, t)
+		}
+	}
+
+	deleted := 0
+	for _, t := range tables {
+		n, err := deleteExpired(t, now, dbc)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	if _, err := deleteExpired(CacheTable(m.Name), now, dbc); err != nil {
+		return deleted, err
+	}
+	return deleted, nil
+}
+
+// deleteExpired deletes the expired keys of the table in chunks. It returns the number of keys deleted
+func deleteExpired(table string, now time.Time, dbc db.DB) (int, error) {
+	deleted := 0
+	for {
+		keys, err := dbc.DeleteExpired(table, now, maxExpiredDeletion)
+		if err != nil {
+			return deleted, fmt.Errorf("error in deleting the expired signals of %s. error: %s", table, err.Error())
+		}
+		deleted += len(keys)
+		if len(keys) < maxExpiredDeletion {
+			return deleted, nil
+		}
+	}
+}
+
+// DeleteExpiredSignals deletes the expired recommendations of all the models. It returns the number of signals
+// deleted
+func DeleteExpiredSignals(now time.Time, dbc db.DB) (int, error) {
+	deleted := 0
+	err := dbc.IterateRecords(tableModels, func(name, value string) error {
+		m, err := DeserializeModel(value)
+		if err != nil {
+			return fmt.Errorf("could not deserialize model %s. error: %s", name, err.Error())
+		}
+		n, err := m.DeleteExpiredSignals(now, dbc)
+		deleted += n
+		return err
+	})
+	return deleted, err
+}
+
+// isExpiringEntry checks if the string stored in the database contains an expiry
+func isExpiringEntry(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "{")
+}
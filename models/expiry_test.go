@@ -0,0 +1,92 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerializeEntry(t *testing.T) {
+	items := []ItemScore{{"item": "1", "score": "0.5"}}
+
+	ser, err := SerializeEntry(items, nil)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, `[{"item":"1","score":"0.5"}]`, ser)
+	assert.Nil(t, EntryExpiry(ser))
+
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	ser, err = SerializeEntry(items, &expiresAt)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, `{"expiresAt":"2030-01-01T00:00:00Z","recommended":[{"item":"1","score":"0.5"}]}`, ser)
+	assert.Equal(t, expiresAt, *EntryExpiry(ser))
+
+	des, exp, err := DeserializeEntry(ser)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, items, des)
+	assert.Equal(t, expiresAt, *exp)
+}
+
+func TestValidateExpiry(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, ValidateExpiry(nil))
+	assert.Nil(t, ValidateExpiry(&future))
+	assert.Equal(t, "expiresAt 2019-01-01T00:00:00Z must be in the future", ValidateExpiry(&past).Error())
+}
+
+func TestDeleteExpiredSignals(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	m, err := NewModel("expiry", "", []string{"userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+
+	expiresAt := time.Now().Add(time.Hour)
+	if _, err := m.StoreRecommendations("1", []ItemScore{{"item": "a"}}, &expiresAt, dbc); err != nil {
+		t.FailNow()
+	}
+	if _, err := m.StoreRecommendations("2", []ItemScore{{"item": "b"}}, &expiresAt, dbc); err != nil {
+		t.FailNow()
+	}
+	// storing without expiry keeps the signal forever
+	if _, err := m.StoreRecommendations("2", []ItemScore{{"item": "c"}}, nil, dbc); err != nil {
+		t.FailNow()
+	}
+
+	// the entries of the shared cache expire as well
+	if err := dbc.AddOne(CacheTable("expiry"), "1", `{"recommended":[{"item":"a"}]}`); err != nil {
+		t.FailNow()
+	}
+	defer dbc.DropTable(CacheTable("expiry"))
+	if err := dbc.SetExpiry(CacheTable("expiry"), "1", expiresAt); err != nil {
+		t.FailNow()
+	}
+
+	n, err := DeleteExpiredSignals(time.Now(), dbc)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = DeleteExpiredSignals(expiresAt.Add(time.Second), dbc)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = dbc.GetOne(m.DataTable(), "1")
+	assert.NotNil(t, err)
+	_, err = dbc.GetOne(CacheTable("expiry"), "1")
+	assert.NotNil(t, err)
+
+	val, err := dbc.GetOne(m.DataTable(), "2")
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"item":"c"}]`, val)
+}
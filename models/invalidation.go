@@ -0,0 +1,66 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/rtlnl/phoenix/pkg/db"
+	"github.com/rtlnl/phoenix/utils"
+)
+
+const (
+	// CacheChannel is the channel where the changes of the recommendations are published for invalidating the
+	// caches of the public instances
+	CacheChannel = "cache"
+	// format of the tables of the cache shared by the public instances holding the serialized recommendations of a model
+	cacheTableFormat = "%s@cache"
+)
+
+// CacheInvalidation is the message published when the recommendations of a model change. An empty signal
+// invalidates all the signals of the model
+type CacheInvalidation struct {
+	ModelName string `json:"modelName"`
+	SignalID  string `json:"signalId,omitempty"`
+}
+
+// CacheTable returns the name of the table of the shared cache holding the recommendations of the model. Its
+// entries expire like the signals and they are deleted by DeleteExpiredSignals
+func CacheTable(modelName string) string {
+	return fmt.Sprintf(cacheTableFormat, modelName)
+}
+
+// InvalidateSignal notifies the subscribers that the cached recommendations of the signal are stale
+func InvalidateSignal(modelName, signalID string, dbc db.DB) error {
+	return publishInvalidation(CacheInvalidation{ModelName: modelName, SignalID: signalID}, dbc)
+}
+
+// InvalidateModel notifies the subscribers that the cached recommendations of all the signals of the model are stale
+func InvalidateModel(modelName string, dbc db.DB) error {
+	return publishInvalidation(CacheInvalidation{ModelName: modelName}, dbc)
+}
+
+// DeserializeCacheInvalidation attempts to convert the published message in a cache invalidation
+func DeserializeCacheInvalidation(s string) (CacheInvalidation, error) {
+	var ci CacheInvalidation
+	if err := json.UnmarshalFromString(s, &ci); err != nil {
+		return CacheInvalidation{}, err
+	}
+	return ci, nil
+}
+
+// InvalidateCache notifies the subscribers that the data of the model changed. Since the data is already changed,
+// a failure is only logged and the caches are refreshed once their entries expire
+func (m *Model) InvalidateCache(dbc db.DB) {
+	if err := InvalidateModel(m.Name, dbc); err != nil {
+		log.Error().Str("MODEL", m.Name).Msgf("could not invalidate the cache. error: %s", err.Error())
+	}
+}
+
+func publishInvalidation(ci CacheInvalidation, dbc db.DB) error {
+	msg, err := utils.SerializeObject(ci)
+	if err != nil {
+		return err
+	}
+	return dbc.Publish(CacheChannel, msg)
+}
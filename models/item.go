@@ -0,0 +1,66 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rtlnl/phoenix/pkg/db"
+	"github.com/rtlnl/phoenix/utils"
+)
+
+// ItemSchema describes the recommended items accepted by a model
+type ItemSchema struct {
+	RequiredKeys []string `json:"requiredKeys,omitempty" description:"keys that every item must have, i.e. ['item', 'score']"`
+	Types        []string `json:"types,omitempty" description:"values accepted for the type of the items. Items without type are accepted unless type is required"`
+	NumericScore bool     `json:"numericScore,omitempty" description:"every item must have a score. Scores are always parsed as numbers"`
+}
+
+// IsEmpty checks if the schema accepts any item
+func (s *ItemSchema) IsEmpty() bool {
+	return len(s.RequiredKeys) == 0 && len(s.Types) == 0 && !s.NumericScore
+}
+
+// Validate checks the items against the schema. The score of the items is always parsed as a number, whether
+// the model has a schema or not. The error lists all the violations with the position of the item starting from 1
+func (s *ItemSchema) Validate(items []ItemScore) error {
+	var msgs []string
+	for i, is := range items {
+		if s != nil {
+			for _, k := range s.RequiredKeys {
+				if is[k] == "" {
+					msgs = append(msgs, fmt.Sprintf("item %d is missing the key %s", i+1, k))
+				}
+			}
+			if t, ok := is["type"]; ok && len(s.Types) > 0 && !utils.StringInSlice(t, s.Types) {
+				msgs = append(msgs, fmt.Sprintf("item %d has type %s. allowed types are %v", i+1, t, s.Types))
+			}
+		}
+		sc, ok := is["score"]
+		if !ok {
+			if s != nil && s.NumericScore {
+				msgs = append(msgs, fmt.Sprintf("item %d is missing the score", i+1))
+			}
+			continue
+		}
+		if _, err := is.Score(); err != nil {
+			msgs = append(msgs, fmt.Sprintf("item %d has score %s that is not a number", i+1, sc))
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("%s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// SetItemSchema updates the schema of the items accepted by the model. An empty schema accepts any item
+func (m *Model) SetItemSchema(schema ItemSchema, dbc db.DB) error {
+	m.ItemSchema = nil
+	if !schema.IsEmpty() {
+		m.ItemSchema = &schema
+	}
+	// store model
+	if _, err := storeModel(*m, dbc); err != nil {
+		return fmt.Errorf("error in storing the itemSchema in database. error: %s", err.Error())
+	}
+	return nil
+}
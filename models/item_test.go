@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItemSchemaValidate(t *testing.T) {
+	schema := &ItemSchema{
+		RequiredKeys: []string{"item", "score"},
+		Types:        []string{"movie", "series"},
+		NumericScore: true,
+	}
+
+	assert.Nil(t, schema.Validate([]ItemScore{
+		{"item": "1", "score": "0.5", "type": "movie"},
+		{"item": "2", "score": "1"},
+	}))
+
+	err := schema.Validate([]ItemScore{
+		{"item": "1", "score": "0.5", "type": "clip"},
+		{"score": "abc"},
+	})
+	assert.Equal(t, "item 1 has type clip. allowed types are [movie series]; item 2 is missing the key item; item 2 has score abc that is not a number", err.Error())
+
+	// models without schema accept any item
+	var empty *ItemSchema
+	assert.Nil(t, empty.Validate([]ItemScore{{"foo": "bar"}}))
+
+	// scores are parsed even without schema
+	err = empty.Validate([]ItemScore{{"item": "1", "score": "0.5"}, {"item": "2", "score": "high"}})
+	assert.Equal(t, "item 2 has score high that is not a number", err.Error())
+
+	// a numeric score requires the score
+	err = (&ItemSchema{NumericScore: true}).Validate([]ItemScore{{"item": "1"}})
+	assert.Equal(t, "item 1 is missing the score", err.Error())
+}
+
+func TestSetItemSchema(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	m, err := NewModel("itemschema", "", []string{"userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+
+	if err := m.SetItemSchema(ItemSchema{RequiredKeys: []string{"item"}}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err := GetModel("itemschema", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, &ItemSchema{RequiredKeys: []string{"item"}}, stored.ItemSchema)
+
+	// an empty schema accepts any item
+	if err := stored.SetItemSchema(ItemSchema{}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err = GetModel("itemschema", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Nil(t, stored.ItemSchema)
+}
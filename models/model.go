@@ -5,19 +5,33 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/rs/zerolog/log"
+
 	"github.com/rtlnl/phoenix/pkg/db"
 	"github.com/rtlnl/phoenix/utils"
 )
 
 const (
 	tableModels = "models"
+	// KindPopular is the kind of the models serving the same global list of items to every signal
+	KindPopular = "popular"
+	// format of the temporary table used when migrating the data to a new signal format
+	migrationTableFormat = "%s@migration"
+	// number of records written to the database at once when migrating the data
+	maxMigrationPipeline = 1000
 )
 
 // Model is the object that acts as container for the metadata of each model
 type Model struct {
-	Name         string   `json:"name" description:"name of the model that will be used"`
-	SignalOrder  []string `json:"signalOrder" description:"list of ordered signals"`
-	Concatenator string   `json:"concatenator" description:"character used as concatenator for SignalOrder {'|','#','_','-'}"`
+	Name         string                `json:"name" description:"name of the model that will be used"`
+	SignalOrder  []string              `json:"signalOrder" description:"list of ordered signals"`
+	Concatenator string                `json:"concatenator" description:"character used as concatenator for SignalOrder {'|','#','_','-'}"`
+	SignalSchema map[string]SignalPart `json:"signalSchema,omitempty" description:"values accepted by each entry of the SignalOrder, i.e. {'userId': {'type': 'integer'}}"`
+	ItemSchema   *ItemSchema           `json:"itemSchema,omitempty" description:"schema of the recommended items accepted by the model"`
+	SortOnUpload bool                  `json:"sortOnUpload,omitempty" description:"sort the recommendations by score when uploading them in batch. Scores must be numbers"`
+	Kind         string                `json:"kind,omitempty" description:"kind of the model. Empty for personalized models or 'popular' for a global list of items"`
+	Version      string                `json:"version,omitempty" description:"version of the data currently served by the model"`
+	Versions     []ModelVersion        `json:"versions,omitempty" description:"history of the versions uploaded in batch, from the oldest to the newest"`
 }
 
 // NewModel is invoked when a new model is created in the database.
@@ -33,8 +47,8 @@ func NewModel(name, concatenator string, signalOrder []string, dbc db.DB) (Model
 	}
 
 	// check if name used is reserved
-	if utils.StringInSlice(name, reservedNames) {
-		return Model{}, fmt.Errorf("cannot use %s as name. this name is reserved", name)
+	if err := ValidateModelName(name); err != nil {
+		return Model{}, err
 	}
 
 	// create model object
@@ -43,13 +57,52 @@ func NewModel(name, concatenator string, signalOrder []string, dbc db.DB) (Model
 		SignalOrder:  signalOrder,
 		Concatenator: concatenator,
 	}
+	return storeModel(model, dbc)
+}
+
+// NewPopularModel is invoked when a new popular model is created in the database. A popular model stores a single
+// list of items under the reserved signal PopularSignalID that is served to every signal
+func NewPopularModel(name string, dbc db.DB) (Model, error) {
+	// if the model exists return error to the client
+	if ModelExists(name, dbc) {
+		return Model{}, fmt.Errorf("model with name %s already exists", name)
+	}
+
+	// check if name used is reserved
+	if err := ValidateModelName(name); err != nil {
+		return Model{}, err
+	}
+
+	// create model object
+	model := Model{
+		Name: name,
+		Kind: KindPopular,
+	}
+	return storeModel(model, dbc)
+}
+
+// ValidateModelName checks that the name can be used for a model. The names of the tables of the models and the
+// containers are reserved, as well as the character @ separating the name of a model from the suffix of its
+// internal tables, i.e. the versions, the expiries and the shared cache
+func ValidateModelName(name string) error {
+	if utils.StringInSlice(name, reservedNames) {
+		return fmt.Errorf("cannot use %s as name. this name is reserved", name)
+	}
+	if strings.Contains(name, tableSeparator) {
+		return fmt.Errorf("cannot use %s as name. the character %s is reserved", name, tableSeparator)
+	}
+	return nil
+}
+
+// storeModel serializes the model and adds it to the models table
+func storeModel(model Model, dbc db.DB) (Model, error) {
 	// serialize it
 	serialized, err := utils.SerializeObject(model)
 	if err != nil {
 		return Model{}, fmt.Errorf("could not serialize model. error: %s", err.Error())
 	}
 	// add to the models table
-	if err := dbc.AddOne(tableModels, name, serialized); err != nil {
+	if err := dbc.AddOne(tableModels, model.Name, serialized); err != nil {
 		return Model{}, err
 	}
 	return model, nil
@@ -83,9 +136,14 @@ func (m *Model) DeleteModel(dbc db.DB) error {
 		return fmt.Errorf("error in removing the model. error: %s", err.Error())
 	}
 	// remove the whole dataset
-	if err := dbc.DropTable(m.Name); err != nil {
+	if err := dbc.DropTable(m.DataTable()); err != nil {
 		return fmt.Errorf("error in deleting the data of the model. error: %s", err.Error())
 	}
+	for _, v := range m.Versions {
+		if err := dbc.DropTable(m.VersionTable(v.BatchID)); err != nil {
+			return fmt.Errorf("error in deleting the data of version %s. error: %s", v.BatchID, err.Error())
+		}
+	}
 	// remove from containers
 	containers, _, err := GetAllContainers(dbc)
 	if err != nil {
@@ -95,6 +153,14 @@ func (m *Model) DeleteModel(dbc db.DB) error {
 		// update models
 		tmp := container.Models
 		container.Models = utils.RemoveElemFromSlice(m.Name, tmp)
+		container.FallbackModels = utils.RemoveElemFromSlice(m.Name, container.FallbackModels)
+		delete(container.Weights, m.Name)
+		if container.Blend != nil {
+			delete(container.Blend.Weights, m.Name)
+		}
+		if container.DefaultModel == m.Name {
+			container.DefaultModel = ""
+		}
 		// store it back
 		ser, err := utils.SerializeObject(container)
 		if err != nil {
@@ -104,25 +170,211 @@ func (m *Model) DeleteModel(dbc db.DB) error {
 			return fmt.Errorf("failed to insert container in database. error: %s", err.Error())
 		}
 	}
+	m.InvalidateCache(dbc)
 	return nil
 }
 
 // UpdateSignalOrder triggers a change in the way the signals are stored
 func (m *Model) UpdateSignalOrder(signalOrder []string, dbc db.DB) error {
-	// delete the data
-	if err := dbc.DropTable(m.Name); err != nil {
+	return m.UpdateSignalFormat(signalOrder, m.Concatenator, false, dbc)
+}
+
+// ModelUpdate contains the settings of a model that can be changed after its creation. The schemas and the
+// sorting are left unchanged when nil
+type ModelUpdate struct {
+	SignalOrder  []string
+	Concatenator string
+	SignalSchema map[string]SignalPart
+	ItemSchema   *ItemSchema
+	SortOnUpload *bool
+	// Migrate re-composes the keys of the data instead of deleting it when the signal format changes
+	Migrate bool
+}
+
+// Update changes the settings of the model and stores it once. The data of the model is migrated or deleted
+// only when the signalOrder or the concatenator change
+func (m *Model) Update(u ModelUpdate, dbc db.DB) error {
+	changed := m.signalFormatChanged(u.SignalOrder, u.Concatenator)
+	if m.IsPopular() && (changed || len(u.SignalSchema) > 0) {
+		return fmt.Errorf("model with name %s is popular and has no signalOrder", m.Name)
+	}
+	if !m.IsPopular() && len(u.SignalOrder) == 0 {
+		return fmt.Errorf("model with name %s is personalized and requires a signalOrder", m.Name)
+	}
+	// validate the schema before touching the data of the model
+	if err := ValidateSignalSchema(u.SignalSchema, u.SignalOrder); err != nil {
+		return err
+	}
+
+	if changed {
+		if err := m.changeSignalFormat(u.SignalOrder, u.Concatenator, u.Migrate, dbc); err != nil {
+			return err
+		}
+	}
+
+	if u.SignalSchema != nil {
+		m.SignalSchema = nil
+		if len(u.SignalSchema) > 0 {
+			m.SignalSchema = u.SignalSchema
+		}
+	}
+	if u.ItemSchema != nil {
+		m.ItemSchema = nil
+		if !u.ItemSchema.IsEmpty() {
+			m.ItemSchema = u.ItemSchema
+		}
+	}
+	if u.SortOnUpload != nil {
+		m.SortOnUpload = *u.SortOnUpload
+	}
+
+	// store model
+	if _, err := storeModel(*m, dbc); err != nil {
+		return fmt.Errorf("error in storing the model in database. error: %s", err.Error())
+	}
+	if changed {
+		m.InvalidateCache(dbc)
+	}
+	return nil
+}
+
+// UpdateSignalFormat changes the signalOrder and the concatenator of the model. When migrate is false the data
+// currently served is deleted, otherwise every key is re-composed following the new format. Only the data
+// currently served is migrated. Nothing changes when the signal format is the same
+func (m *Model) UpdateSignalFormat(signalOrder []string, concatenator string, migrate bool, dbc db.DB) error {
+	if !m.signalFormatChanged(signalOrder, concatenator) {
+		return nil
+	}
+	if err := m.changeSignalFormat(signalOrder, concatenator, migrate, dbc); err != nil {
+		return err
+	}
+	// store model
+	if _, err := storeModel(*m, dbc); err != nil {
+		return fmt.Errorf("error in storing the signalOrder in database. error: %s", err.Error())
+	}
+	m.InvalidateCache(dbc)
+	return nil
+}
+
+// signalFormatChanged checks if the signalOrder or the concatenator differ from the ones of the model
+func (m *Model) signalFormatChanged(signalOrder []string, concatenator string) bool {
+	if concatenator != m.Concatenator || len(signalOrder) != len(m.SignalOrder) {
+		return true
+	}
+	for i, s := range signalOrder {
+		if s != m.SignalOrder[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// changeSignalFormat migrates or deletes the data of the model and sets the new signal format without storing
+// the model
+func (m *Model) changeSignalFormat(signalOrder []string, concatenator string, migrate bool, dbc db.DB) error {
+	if m.IsPopular() {
+		return fmt.Errorf("model with name %s is popular and has no signalOrder", m.Name)
+	}
+	// validate model's parameters
+	if len(signalOrder) > 1 && concatenator == "" {
+		return errors.New("multiple signals are being specified but no concatenator. concatenator is missing")
+	}
+
+	if migrate {
+		if err := m.migrateSignals(signalOrder, concatenator, dbc); err != nil {
+			return err
+		}
+	} else if err := dbc.DropTable(m.DataTable()); err != nil {
+		// delete the data
 		return fmt.Errorf("error in deleting the data of the model. error: %s", err.Error())
 	}
-	// change signalType
+
+	// change signal format
 	m.SignalOrder = signalOrder
-	// serialize model
-	model, err := utils.SerializeObject(m)
-	if err != nil {
-		return fmt.Errorf("failed serialization of the model. error: %s", err.Error())
+	m.Concatenator = concatenator
+	// the schema of the parts no longer in the signalOrder is dropped
+	for name := range m.SignalSchema {
+		if !utils.StringInSlice(name, signalOrder) {
+			delete(m.SignalSchema, name)
+		}
+	}
+	if len(m.SignalSchema) == 0 {
+		m.SignalSchema = nil
 	}
+	return nil
+}
+
+// SetSortOnUpload changes whether the recommendations uploaded in batch are sorted by score
+func (m *Model) SetSortOnUpload(sort bool, dbc db.DB) error {
+	m.SortOnUpload = sort
 	// store model
-	if err := dbc.AddOne(tableModels, m.Name, model); err != nil {
-		return fmt.Errorf("error in storing the signalOrder in database. error: %s", err.Error())
+	if _, err := storeModel(*m, dbc); err != nil {
+		return fmt.Errorf("error in storing the sortOnUpload flag in database. error: %s", err.Error())
+	}
+	return nil
+}
+
+// migrateSignals writes the data of the model in a temporary table with the keys following the new format and
+// swaps it with the data currently served. The new signalOrder must contain the same signals of the current one
+func (m *Model) migrateSignals(signalOrder []string, concatenator string, dbc db.DB) error {
+	position := make(map[string]int, len(m.SignalOrder))
+	for i, s := range m.SignalOrder {
+		position[s] = i
+	}
+	if len(signalOrder) != len(m.SignalOrder) {
+		return fmt.Errorf("cannot migrate the data. signalOrder %v must contain the same signals of %v", signalOrder, m.SignalOrder)
+	}
+	for _, s := range signalOrder {
+		if _, ok := position[s]; !ok {
+			return fmt.Errorf("cannot migrate the data. signalOrder %v must contain the same signals of %v", signalOrder, m.SignalOrder)
+		}
+	}
+
+	table := fmt.Sprintf(migrationTableFormat, m.DataTable())
+	migrated := 0
+	err := dbc.IterateRecords(m.DataTable(), func(key, value string) error {
+		parts := []string{key}
+		if m.Concatenator != "" {
+			parts = strings.Split(key, m.Concatenator)
+		}
+		if len(parts) != len(m.SignalOrder) {
+			log.Warn().Str("SIGNAL", key).Str("MODEL", m.Name).Msg("MIGRATE skipped signal not formatted correctly")
+			return nil
+		}
+		// re-compose the key following the new order
+		signals := make([]string, len(signalOrder))
+		for i, s := range signalOrder {
+			signals[i] = parts[position[s]]
+		}
+		key = strings.Join(signals, concatenator)
+		dbc.PipelineAddOne(table, key, value)
+		// keep the expiry of the signal
+		if expiresAt := EntryExpiry(value); expiresAt != nil {
+			dbc.PipelineSetExpiry(table, key, *expiresAt)
+		}
+
+		migrated++
+		if migrated%maxMigrationPipeline == 0 {
+			return dbc.PipelineExec()
+		}
+		return nil
+	})
+	if err == nil {
+		err = dbc.PipelineExec()
+	}
+	if err != nil {
+		if err := dbc.DropTable(table); err != nil {
+			log.Error().Msg(err.Error())
+		}
+		return fmt.Errorf("error in migrating the data of the model. error: %s", err.Error())
+	}
+
+	// nothing to swap
+	if migrated == 0 {
+		return dbc.DropTable(m.DataTable())
+	}
+	if err := dbc.RenameTable(table, m.DataTable()); err != nil {
+		return fmt.Errorf("error in migrating the data of the model. error: %s", err.Error())
 	}
 	return nil
 }
@@ -135,8 +387,36 @@ func (m *Model) RequireSignalFormat() bool {
 	return false
 }
 
-// CorrectSignalFormat checks that the signal format is correct
+// IsPopular checks if the model serves a global list of items instead of personalized ones
+func (m *Model) IsPopular() bool {
+	return m.Kind == KindPopular
+}
+
+// SignalKey returns the key under which the recommendations of the signal are stored
+func (m *Model) SignalKey(s string) string {
+	if m.IsPopular() {
+		return PopularSignalID
+	}
+	return s
+}
+
+// ValidateReservedSignal checks if the signal can be stored in the model. Popular models accept only the reserved
+// signal PopularSignalID while the personalized models cannot use any of the reserved signals
+func (m *Model) ValidateReservedSignal(s string) error {
+	if m.IsPopular() && s != PopularSignalID {
+		return fmt.Errorf("popular models accept only the signal %s", PopularSignalID)
+	}
+	if !m.IsPopular() && utils.StringInSlice(s, reservedSignals) {
+		return fmt.Errorf("cannot use %s as signal. this signal is reserved", s)
+	}
+	return nil
+}
+
+// CorrectSignalFormat checks that the signal format is correct. Popular models accept any signal
 func (m *Model) CorrectSignalFormat(s string) bool {
+	if m.IsPopular() {
+		return true
+	}
 	res := strings.FieldsFunc(s, func(c rune) bool {
 		r := []rune(m.Concatenator)
 		if len(r) == 0 {
@@ -147,6 +427,29 @@ func (m *Model) CorrectSignalFormat(s string) bool {
 	return len(m.SignalOrder) == len(res)
 }
 
+// BuildSignal composes the signal from its named parts following the SignalOrder and the Concatenator of the model.
+// Parts that are not in the SignalOrder are ignored. Popular models accept any signal hence PopularSignalID is returned
+func (m *Model) BuildSignal(parts map[string]string) (string, error) {
+	if m.IsPopular() {
+		return PopularSignalID, nil
+	}
+	values := make([]string, len(m.SignalOrder))
+	for i, s := range m.SignalOrder {
+		v := parts[s]
+		if v == "" {
+			return "", fmt.Errorf("signal part %s is missing", s)
+		}
+		if m.Concatenator != "" && strings.Contains(v, m.Concatenator) {
+			return "", fmt.Errorf("signal part %s cannot contain the concatenator %s", s, m.Concatenator)
+		}
+		if err := m.validateSignalPart(s, v); err != nil {
+			return "", err
+		}
+		values[i] = v
+	}
+	return strings.Join(values, m.Concatenator), nil
+}
+
 // GetAllModels is a convenient functions to get all the models from DB
 func GetAllModels(dbc db.DB) ([]Model, int, error) {
 	var models []Model
@@ -167,7 +470,7 @@ func GetAllModels(dbc db.DB) ([]Model, int, error) {
 
 // GetDataPreview returns a limited amount of data as preview for a single model
 func (m *Model) GetDataPreview(dbc db.DB) (map[string]string, int, error) {
-	records, count, err := dbc.GetAllRecords(m.Name)
+	records, count, err := dbc.GetAllRecords(m.DataTable())
 	if err != nil {
 		return nil, -1, fmt.Errorf("error in returning the data preview from the database. error: %s", err.Error())
 	}
@@ -82,6 +82,43 @@ func TestNewModelReservedWorld(t *testing.T) {
 	}
 
 	assert.Equal(t, "cannot use models as name. this name is reserved", err.Error())
+
+	// the names of the internal tables of the models are reserved
+	_, err = NewModel("foo@expiry", "", []string{"articleId"}, dbc)
+	assert.Equal(t, "cannot use foo@expiry as name. the character @ is reserved", err.Error())
+}
+
+func TestNewPopularModel(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	m, err := NewPopularModel("trending", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	assert.Equal(t, "trending", m.Name)
+	assert.Equal(t, KindPopular, m.Kind)
+	assert.Equal(t, true, m.IsPopular())
+	assert.Equal(t, PopularSignalID, m.SignalKey("123"))
+	assert.Equal(t, true, m.CorrectSignalFormat("123_456"))
+
+	_, err = NewPopularModel("containers", dbc)
+	assert.Equal(t, "cannot use containers as name. this name is reserved", err.Error())
+
+	_, err = NewPopularModel("trending@cache", dbc)
+	assert.Equal(t, "cannot use trending@cache as name. the character @ is reserved", err.Error())
+}
+
+func TestValidateReservedSignal(t *testing.T) {
+	popular := Model{Name: "popular", Kind: KindPopular}
+	personalized := Model{Name: "personalized", SignalOrder: []string{"userId"}}
+
+	assert.Nil(t, popular.ValidateReservedSignal(PopularSignalID))
+	assert.Equal(t, "popular models accept only the signal _popular", popular.ValidateReservedSignal("123").Error())
+	assert.Nil(t, personalized.ValidateReservedSignal("123"))
+	assert.Equal(t, "cannot use _popular as signal. this signal is reserved", personalized.ValidateReservedSignal(PopularSignalID).Error())
+	assert.Equal(t, "123", personalized.SignalKey("123"))
 }
 
 func TestGetModel(t *testing.T) {
@@ -155,6 +192,52 @@ func TestUpdateSignalOrder(t *testing.T) {
 	assert.EqualValues(t, so, m.SignalOrder)
 }
 
+func TestUpdateSignalFormatMigrate(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	m, err := NewModel("migrate", "_", []string{"a", "b"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	if err := dbc.AddOne(m.DataTable(), "1_2", "[1]"); err != nil {
+		t.FailNow()
+	}
+	if err := dbc.AddOne(m.DataTable(), "3_4", "[3]"); err != nil {
+		t.FailNow()
+	}
+	if err := dbc.AddOne(m.DataTable(), "5", "[5]"); err != nil {
+		t.FailNow()
+	}
+
+	if err := m.UpdateSignalFormat([]string{"b", "a"}, "|", true, dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err := GetModel("migrate", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.EqualValues(t, []string{"b", "a"}, stored.SignalOrder)
+	assert.Equal(t, "|", stored.Concatenator)
+
+	val, err := dbc.GetOne(stored.DataTable(), "2|1")
+	assert.Nil(t, err)
+	assert.Equal(t, "[1]", val)
+	val, err = dbc.GetOne(stored.DataTable(), "4|3")
+	assert.Nil(t, err)
+	assert.Equal(t, "[3]", val)
+
+	// the old keys and the ones not formatted correctly are gone
+	_, err = dbc.GetOne(stored.DataTable(), "1_2")
+	assert.NotNil(t, err)
+	_, err = dbc.GetOne(stored.DataTable(), "5")
+	assert.NotNil(t, err)
+
+	err = m.UpdateSignalFormat([]string{"b", "c"}, "|", true, dbc)
+	assert.Equal(t, "cannot migrate the data. signalOrder [b c] must contain the same signals of [b a]", err.Error())
+}
+
 func TestRequireSignalFormat(t *testing.T) {
 	dbc, c := GetTestRedisClient()
 	defer c()
@@ -256,6 +339,29 @@ func TestCorrectSignalFormat(t *testing.T) {
 	}
 }
 
+func TestBuildSignal(t *testing.T) {
+	m := Model{
+		Name:         "test",
+		SignalOrder:  []string{"userId", "articleId"},
+		Concatenator: "|",
+	}
+
+	signal, err := m.BuildSignal(map[string]string{"articleId": "2", "userId": "1", "device": "tv"})
+	assert.Nil(t, err)
+	assert.Equal(t, "1|2", signal)
+
+	_, err = m.BuildSignal(map[string]string{"userId": "1"})
+	assert.Equal(t, "signal part articleId is missing", err.Error())
+
+	_, err = m.BuildSignal(map[string]string{"userId": "1|3", "articleId": "2"})
+	assert.Equal(t, "signal part userId cannot contain the concatenator |", err.Error())
+
+	p := Model{Name: "popular", Kind: KindPopular}
+	signal, err = p.BuildSignal(map[string]string{"userId": "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, PopularSignalID, signal)
+}
+
 func TestDeserializeModel(t *testing.T) {
 	ser := `{"name":"test","signalOrder":["article","signal"],"concatenator":"_"}`
 	m, err := DeserializeModel(ser)
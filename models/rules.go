@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/rtlnl/phoenix/utils"
+)
+
+// Rules contains the business rules applied to the recommendations of a container before serving them
+type Rules struct {
+	ExcludeTypes    []string       `json:"excludeTypes,omitempty" description:"items with one of these types are not served"`
+	BlockedItems    []string       `json:"blockedItems,omitempty" description:"IDs of the items that are not served"`
+	MaxItemsPerType map[string]int `json:"maxItemsPerType,omitempty" description:"maximum number of items served per type, i.e. {'movie': 3}"`
+	MinScore        *float64       `json:"minScore,omitempty" description:"items with a lower score are not served. Any threshold, also zero or negative, is applied when set"`
+}
+
+// Validate checks that the rules can be applied
+func (r *Rules) Validate() error {
+	for t, max := range r.MaxItemsPerType {
+		if max < 1 {
+			return fmt.Errorf("maximum number of items of type %s must be at least 1. use excludeTypes for hiding a type", t)
+		}
+	}
+	return nil
+}
+
+// IsEmpty checks if none of the rules is set
+func (r *Rules) IsEmpty() bool {
+	return len(r.ExcludeTypes) == 0 && len(r.BlockedItems) == 0 && len(r.MaxItemsPerType) == 0 && r.MinScore == nil
+}
+
+// Apply returns the items that satisfy the rules keeping their order. Items without a valid score are filtered
+// out when a minimum score is set
+func (r *Rules) Apply(items []ItemScore) []ItemScore {
+	if r == nil || r.IsEmpty() {
+		return items
+	}
+
+	perType := make(map[string]int)
+	filtered := make([]ItemScore, 0, len(items))
+	for _, is := range items {
+		if utils.StringInSlice(is["type"], r.ExcludeTypes) || utils.StringInSlice(is["item"], r.BlockedItems) {
+			continue
+		}
+		if r.MinScore != nil {
+			score, err := is.Score()
+			if err != nil || score < *r.MinScore {
+				continue
+			}
+		}
+		if max, ok := r.MaxItemsPerType[is["type"]]; ok {
+			if perType[is["type"]] >= max {
+				continue
+			}
+			perType[is["type"]]++
+		}
+		filtered = append(filtered, is)
+	}
+	return filtered
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRulesApply(t *testing.T) {
+	items := []ItemScore{
+		{"item": "1", "score": "0.9", "type": "movie"},
+		{"item": "2", "score": "0.8", "type": "series"},
+		{"item": "3", "score": "0.7", "type": "movie"},
+		{"item": "4", "score": "0.6", "type": "clip"},
+		{"item": "5", "score": "0.2", "type": "movie"},
+		{"item": "6", "score": "abc", "type": "series"},
+	}
+
+	minScore, half, zero := 0.65, 0.5, 0.0
+	tests := map[string]struct {
+		rules    *Rules
+		expected []string
+	}{
+		"no rules": {
+			rules:    nil,
+			expected: []string{"1", "2", "3", "4", "5", "6"},
+		},
+		"exclude types": {
+			rules:    &Rules{ExcludeTypes: []string{"movie", "clip"}},
+			expected: []string{"2", "6"},
+		},
+		"blocked items": {
+			rules:    &Rules{BlockedItems: []string{"1", "4"}},
+			expected: []string{"2", "3", "5", "6"},
+		},
+		"max items per type": {
+			rules:    &Rules{MaxItemsPerType: map[string]int{"movie": 2, "series": 1}},
+			expected: []string{"1", "2", "3", "4"},
+		},
+		"min score": {
+			rules:    &Rules{MinScore: &minScore},
+			expected: []string{"1", "2", "3"},
+		},
+		"zero min score": {
+			rules:    &Rules{MinScore: &zero},
+			expected: []string{"1", "2", "3", "4", "5"},
+		},
+		"combined": {
+			rules:    &Rules{BlockedItems: []string{"1"}, MaxItemsPerType: map[string]int{"movie": 1}, MinScore: &half},
+			expected: []string{"2", "3", "4"},
+		},
+	}
+	for testName, test := range tests {
+		t.Logf("Running test case %s", testName)
+		var res []string
+		for _, is := range test.rules.Apply(items) {
+			res = append(res, is["item"])
+		}
+		assert.Equal(t, test.expected, res)
+	}
+
+	// the items in input are not modified
+	assert.Equal(t, 6, len(items))
+}
+
+func TestSetRules(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	container, err := NewContainer("rules", "campaign", nil, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	err = container.SetRules(Rules{MaxItemsPerType: map[string]int{"movie": 0}}, dbc)
+	assert.Equal(t, "maximum number of items of type movie must be at least 1. use excludeTypes for hiding a type", err.Error())
+
+	if err := container.SetRules(Rules{ExcludeTypes: []string{"clip"}}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err := GetContainer("rules", "campaign", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, []string{"clip"}, stored.Rules.ExcludeTypes)
+
+	// empty rules remove the filtering
+	if err := stored.SetRules(Rules{}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err = GetContainer("rules", "campaign", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Nil(t, stored.Rules)
+}
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/rtlnl/phoenix/pkg/db"
+	"github.com/rtlnl/phoenix/utils"
+)
+
+const (
+	tableSchedules = "schedules"
+)
+
+// ScheduledChange is a change of the models linked to a container that the worker applies at a given time
+type ScheduledChange struct {
+	ID               string    `json:"id" description:"identifier of the change used for cancelling it"`
+	PublicationPoint string    `json:"publicationPoint" description:"publication point of the container"`
+	Campaign         string    `json:"campaign" description:"campaign of the container"`
+	Models           []string  `json:"models" description:"models linked to the container once the change is applied. Linked models not in the list are unlinked"`
+	ApplyAt          time.Time `json:"applyAt" description:"time from which the change is applied"`
+	CreatedAt        time.Time `json:"createdAt" description:"time when the change has been scheduled"`
+}
+
+// NewScheduledChange schedules the replacement of the models linked to the container at the given time
+func NewScheduledChange(publicationPoint, campaign string, models []string, applyAt time.Time, dbc db.DB) (ScheduledChange, error) {
+	if !ContainerExists(publicationPoint, campaign, dbc) {
+		return ScheduledChange{}, fmt.Errorf("container with publication point %s and campaign %s not found", publicationPoint, campaign)
+	}
+	if !applyAt.After(time.Now()) {
+		return ScheduledChange{}, fmt.Errorf("applyAt %s must be in the future", applyAt.Format(time.RFC3339))
+	}
+	for _, m := range models {
+		if !ModelExists(m, dbc) {
+			return ScheduledChange{}, fmt.Errorf("model with name %s not found", m)
+		}
+	}
+
+	sc := ScheduledChange{
+		ID:               uuid.New().String(),
+		PublicationPoint: publicationPoint,
+		Campaign:         campaign,
+		Models:           utils.RemoveEmptyValueInSlice(models),
+		ApplyAt:          applyAt,
+		CreatedAt:        time.Now(),
+	}
+	if err := sc.save(dbc); err != nil {
+		return ScheduledChange{}, err
+	}
+	return sc, nil
+}
+
+// GetScheduledChanges returns the changes not yet applied sorted by time. Empty publication point and campaign
+// return the changes of all the containers
+func GetScheduledChanges(publicationPoint, campaign string, dbc db.DB) ([]ScheduledChange, error) {
+	return scheduledChanges(publicationPoint, campaign, func(id string, err error) error {
+		return fmt.Errorf("could not deserialize scheduled change %s. error: %s", id, err.Error())
+	}, dbc)
+}
+
+// scheduledChanges returns the changes of the container sorted by time. The entries that cannot be deserialized
+// are passed to invalid that decides whether to skip them, returning nil, or to fail
+func scheduledChanges(publicationPoint, campaign string, invalid func(id string, err error) error, dbc db.DB) ([]ScheduledChange, error) {
+	var changes []ScheduledChange
+	err := dbc.IterateRecords(tableSchedules, func(id, value string) error {
+		sc, err := DeserializeScheduledChange(value)
+		if err != nil {
+			return invalid(id, err)
+		}
+		if (publicationPoint == "" || sc.PublicationPoint == publicationPoint) && (campaign == "" || sc.Campaign == campaign) {
+			changes = append(changes, sc)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].ApplyAt.Before(changes[j].ApplyAt)
+	})
+	return changes, nil
+}
+
+// CancelScheduledChange deletes a change not yet applied
+func CancelScheduledChange(id string, dbc db.DB) (ScheduledChange, error) {
+	v, err := dbc.GetOne(tableSchedules, id)
+	if err != nil {
+		return ScheduledChange{}, fmt.Errorf("scheduled change %s not found", id)
+	}
+	sc, err := DeserializeScheduledChange(v)
+	if err != nil {
+		return ScheduledChange{}, err
+	}
+	if err := dbc.DeleteOne(tableSchedules, id); err != nil {
+		return ScheduledChange{}, err
+	}
+	return sc, nil
+}
+
+// ApplyScheduledChanges applies to the containers the changes due until the time in input in the order they
+// are scheduled. Changes that cannot be applied, i.e. because the container has been deleted, are discarded
+// and reported in the error. Entries that cannot be deserialized are skipped and logged. It returns the number
+// of changes applied
+func ApplyScheduledChanges(now time.Time, dbc db.DB) (int, error) {
+	changes, err := scheduledChanges("", "", func(id string, err error) error {
+		log.Error().Str("SCHEDULE", id).Msgf("could not deserialize scheduled change. error: %s", err.Error())
+		return nil
+	}, dbc)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	var msgs []string
+	for _, sc := range changes {
+		if sc.ApplyAt.After(now) {
+			break
+		}
+		if err := sc.apply(dbc); err != nil {
+			msgs = append(msgs, fmt.Sprintf("could not apply scheduled change %s. error: %s", sc.ID, err.Error()))
+		} else {
+			applied++
+		}
+		// the change is applied only once
+		if err := dbc.DeleteOne(tableSchedules, sc.ID); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) > 0 {
+		return applied, fmt.Errorf("%s", strings.Join(msgs, "; "))
+	}
+	return applied, nil
+}
+
+// moveScheduledChanges assigns the changes not yet applied of a container to its new publication point and campaign
+func moveScheduledChanges(publicationPoint, campaign, newPublicationPoint, newCampaign string, dbc db.DB) error {
+	changes, err := GetScheduledChanges(publicationPoint, campaign, dbc)
+	if err != nil {
+		return err
+	}
+	for _, sc := range changes {
+		sc.PublicationPoint = newPublicationPoint
+		sc.Campaign = newCampaign
+		if err := sc.save(dbc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteScheduledChanges deletes the changes not yet applied of a container. Entries that cannot be deserialized
+// are left untouched
+func deleteScheduledChanges(publicationPoint, campaign string, dbc db.DB) error {
+	changes, err := scheduledChanges(publicationPoint, campaign, func(id string, err error) error {
+		return nil
+	}, dbc)
+	if err != nil {
+		return err
+	}
+	for _, sc := range changes {
+		if err := dbc.DeleteOne(tableSchedules, sc.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// save stores the scheduled change in the database
+func (sc *ScheduledChange) save(dbc db.DB) error {
+	// serialize scheduled change
+	serialized, err := utils.SerializeObject(sc)
+	if err != nil {
+		return fmt.Errorf("could not serialize scheduled change. error: %s", err.Error())
+	}
+	return dbc.AddOne(tableSchedules, sc.ID, serialized)
+}
+
+// apply replaces the models of the container with the ones of the change
+func (sc *ScheduledChange) apply(dbc db.DB) error {
+	c, err := GetContainer(sc.PublicationPoint, sc.Campaign, dbc)
+	if err != nil {
+		return err
+	}
+	return c.SetModels(sc.Models, dbc)
+}
+
+// DeserializeScheduledChange attempts to convert the string in input in a scheduled change
+func DeserializeScheduledChange(s string) (ScheduledChange, error) {
+	var sc ScheduledChange
+	if err := json.UnmarshalFromString(s, &sc); err != nil {
+		return ScheduledChange{}, err
+	}
+	return sc, nil
+}
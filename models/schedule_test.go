@@ -0,0 +1,175 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduledChange(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	regular, err := NewModel("regular", "", []string{"signal"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer regular.DeleteModel(dbc)
+
+	holiday, err := NewModel("holiday", "", []string{"signal"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer holiday.DeleteModel(dbc)
+
+	container, err := NewContainer("scheduled", "campaign", []string{"regular"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer container.DeleteContainer(dbc)
+
+	if err := container.SetWeights(map[string]int{"regular": 100}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	applyAt := time.Now().Add(time.Hour)
+	sc, err := NewScheduledChange("scheduled", "campaign", []string{"holiday"}, applyAt, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	cancelled, err := NewScheduledChange("scheduled", "campaign", []string{"regular", "holiday"}, applyAt.Add(time.Hour), dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	changes, err := GetScheduledChanges("scheduled", "campaign", dbc)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(changes))
+	assert.Equal(t, sc.ID, changes[0].ID)
+
+	if _, err := CancelScheduledChange(cancelled.ID, dbc); err != nil {
+		t.FailNow()
+	}
+
+	// nothing is applied before the time is reached
+	n, err := ApplyScheduledChanges(time.Now(), dbc)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = ApplyScheduledChanges(applyAt, dbc)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	stored, err := GetContainer("scheduled", "campaign", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, []string{"holiday"}, stored.Models)
+	assert.Empty(t, stored.Weights)
+
+	changes, err = GetScheduledChanges("", "", dbc)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(changes))
+}
+
+func TestDeleteContainerScheduledChanges(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	container, err := NewContainer("deletedschedule", "campaign", nil, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	other, err := NewContainer("keptschedule", "campaign", nil, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer other.DeleteContainer(dbc)
+
+	applyAt := time.Now().Add(time.Hour)
+	if _, err := NewScheduledChange("deletedschedule", "campaign", nil, applyAt, dbc); err != nil {
+		t.FailNow()
+	}
+	if _, err := NewScheduledChange("keptschedule", "campaign", nil, applyAt, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if err := container.DeleteContainer(dbc); err != nil {
+		t.FailNow()
+	}
+
+	changes, err := GetScheduledChanges("deletedschedule", "campaign", dbc)
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	changes, err = GetScheduledChanges("keptschedule", "campaign", dbc)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(changes))
+}
+
+func TestScheduledChangeFails(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	container, err := NewContainer("unscheduled", "campaign", nil, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer container.DeleteContainer(dbc)
+
+	_, err = NewScheduledChange("unscheduled", "campaign", nil, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), dbc)
+	assert.Equal(t, "applyAt 2019-01-01T00:00:00Z must be in the future", err.Error())
+
+	_, err = NewScheduledChange("unscheduled", "campaign", []string{"missing"}, time.Now().Add(time.Hour), dbc)
+	assert.Equal(t, "model with name missing not found", err.Error())
+
+	_, err = NewScheduledChange("missing", "campaign", nil, time.Now().Add(time.Hour), dbc)
+	assert.Equal(t, "container with publication point missing and campaign campaign not found", err.Error())
+
+	_, err = CancelScheduledChange("missing", dbc)
+	assert.Equal(t, "scheduled change missing not found", err.Error())
+
+	_, err = NewModel("schedules", "", []string{"articleId"}, dbc)
+	assert.Equal(t, "cannot use schedules as name. this name is reserved", err.Error())
+}
+
+func TestApplyScheduledChangesSkipsInvalid(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	m, err := NewModel("skipped", "", []string{"signal"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+
+	container, err := NewContainer("skipping", "campaign", nil, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer container.DeleteContainer(dbc)
+
+	applyAt := time.Now().Add(time.Hour)
+	if _, err := NewScheduledChange("skipping", "campaign", []string{"skipped"}, applyAt, dbc); err != nil {
+		t.FailNow()
+	}
+	if err := dbc.AddOne(tableSchedules, "invalid", "not a change"); err != nil {
+		t.FailNow()
+	}
+	defer dbc.DeleteOne(tableSchedules, "invalid")
+
+	_, err = GetScheduledChanges("", "", dbc)
+	assert.NotNil(t, err)
+
+	n, err := ApplyScheduledChanges(applyAt, dbc)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	stored, err := GetContainer("skipping", "campaign", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, []string{"skipped"}, stored.Models)
+}
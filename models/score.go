@@ -0,0 +1,64 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Score parses the score of the item as a float
+func (is ItemScore) Score() (float64, error) {
+	return strconv.ParseFloat(is["score"], 64)
+}
+
+// ValidateScores checks that the score of every item is a number. The error lists all the items with the position
+// of the item starting from 1
+func ValidateScores(items []ItemScore) error {
+	var msgs []string
+	for i, is := range items {
+		if _, err := is.Score(); err != nil {
+			msgs = append(msgs, fmt.Sprintf("item %d has score %s that is not a number", i+1, is["score"]))
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("%s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// SortByScore returns the items sorted by score from the highest to the lowest. Items without a valid score are
+// placed at the end keeping their order
+func SortByScore(items []ItemScore) []ItemScore {
+	scores := make([]float64, len(items))
+	valid := make([]bool, len(items))
+	idx := make([]int, len(items))
+	for i, is := range items {
+		s, err := is.Score()
+		scores[i], valid[i], idx[i] = s, err == nil, i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		i, j := idx[a], idx[b]
+		if valid[i] != valid[j] {
+			return valid[i]
+		}
+		return scores[i] > scores[j]
+	})
+	sorted := make([]ItemScore, len(items))
+	for i, j := range idx {
+		sorted[i] = items[j]
+	}
+	return sorted
+}
+
+// FilterByScore returns the items with a score greater or equal than the minimum keeping their order. Items
+// without a valid score are filtered out
+func FilterByScore(items []ItemScore, min float64) []ItemScore {
+	filtered := make([]ItemScore, 0, len(items))
+	for _, is := range items {
+		if s, err := is.Score(); err == nil && s >= min {
+			filtered = append(filtered, is)
+		}
+	}
+	return filtered
+}
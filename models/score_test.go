@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortByScore(t *testing.T) {
+	items := []ItemScore{
+		{"item": "1", "score": "0.2"},
+		{"item": "2", "score": "abc"},
+		{"item": "3", "score": "0.9"},
+		{"item": "4"},
+		{"item": "5", "score": "0.5"},
+	}
+
+	var res []string
+	for _, is := range SortByScore(items) {
+		res = append(res, is["item"])
+	}
+	assert.Equal(t, []string{"3", "5", "1", "2", "4"}, res)
+
+	// the items in input are not modified
+	assert.Equal(t, "1", items[0]["item"])
+}
+
+func TestFilterByScore(t *testing.T) {
+	items := []ItemScore{
+		{"item": "1", "score": "0.2"},
+		{"item": "2", "score": "abc"},
+		{"item": "3", "score": "0.9"},
+		{"item": "4", "score": "0.5"},
+	}
+
+	var res []string
+	for _, is := range FilterByScore(items, 0.5) {
+		res = append(res, is["item"])
+	}
+	assert.Equal(t, []string{"3", "4"}, res)
+}
+
+func TestValidateScores(t *testing.T) {
+	assert.Nil(t, ValidateScores([]ItemScore{{"item": "1", "score": "1e-3"}}))
+
+	err := ValidateScores([]ItemScore{{"item": "1", "score": "0.1"}, {"item": "2"}, {"item": "3", "score": "high"}})
+	assert.Equal(t, "item 2 has score  that is not a number; item 3 has score high that is not a number", err.Error())
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rtlnl/phoenix/pkg/db"
+	"github.com/rtlnl/phoenix/utils"
+)
+
+const (
+	// SignalPartString accepts any non empty value
+	SignalPartString = "string"
+	// SignalPartInteger accepts only integer values
+	SignalPartInteger = "integer"
+	// SignalPartUUID accepts only UUIDs in their canonical form
+	SignalPartUUID = "uuid"
+	// SignalPartEnum accepts only the values listed in the part
+	SignalPartEnum = "enum"
+	// SignalPartRegex accepts only the values matching the pattern of the part
+	SignalPartRegex = "regex"
+)
+
+var (
+	signalPartTypes = []string{SignalPartString, SignalPartInteger, SignalPartUUID, SignalPartEnum, SignalPartRegex}
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	// compiled patterns of the regex parts. The signals are validated on every request so the patterns are
+	// compiled only once
+	signalPatterns sync.Map
+)
+
+// SignalPart describes the values accepted by an entry of the SignalOrder
+type SignalPart struct {
+	Type    string   `json:"type" description:"one of string, integer, uuid, enum or regex"`
+	Values  []string `json:"values,omitempty" description:"values accepted by the enum type"`
+	Pattern string   `json:"pattern,omitempty" description:"regular expression that the values of the regex type must match"`
+}
+
+// Validate checks that the part can be used for validating the signals
+func (p *SignalPart) Validate() error {
+	if !utils.StringInSlice(p.Type, signalPartTypes) {
+		return fmt.Errorf("signal part type %s not supported. use one of %v", p.Type, signalPartTypes)
+	}
+	if p.Type == SignalPartEnum && len(p.Values) == 0 {
+		return fmt.Errorf("signal part type %s requires at least one value", p.Type)
+	}
+	if p.Type == SignalPartRegex {
+		if _, err := compilePattern(p.Pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check returns an error if the value is not accepted by the part
+func (p *SignalPart) Check(v string) error {
+	switch p.Type {
+	case SignalPartInteger:
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return fmt.Errorf("must be an integer, got %s", v)
+		}
+	case SignalPartUUID:
+		if !uuidPattern.MatchString(v) {
+			return fmt.Errorf("must be a UUID, got %s", v)
+		}
+	case SignalPartEnum:
+		if !utils.StringInSlice(v, p.Values) {
+			return fmt.Errorf("must be one of %v, got %s", p.Values, v)
+		}
+	case SignalPartRegex:
+		re, err := compilePattern(p.Pattern)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(v) {
+			return fmt.Errorf("must match %s, got %s", p.Pattern, v)
+		}
+	}
+	return nil
+}
+
+// ValidateSignalSchema checks that the schema describes only the entries of the signalOrder and that all its parts
+// are valid
+func ValidateSignalSchema(schema map[string]SignalPart, signalOrder []string) error {
+	for name, p := range schema {
+		if !utils.StringInSlice(name, signalOrder) {
+			return fmt.Errorf("signal part %s is not in the signalOrder %v", name, signalOrder)
+		}
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetSignalSchema updates the schema used for validating each part of the signals. An empty schema removes the
+// validation of the parts
+func (m *Model) SetSignalSchema(schema map[string]SignalPart, dbc db.DB) error {
+	if m.IsPopular() && len(schema) > 0 {
+		return fmt.Errorf("model with name %s is popular and has no signalOrder", m.Name)
+	}
+	if err := ValidateSignalSchema(schema, m.SignalOrder); err != nil {
+		return err
+	}
+	m.SignalSchema = nil
+	if len(schema) > 0 {
+		m.SignalSchema = schema
+	}
+	// store model
+	if _, err := storeModel(*m, dbc); err != nil {
+		return fmt.Errorf("error in storing the signalSchema in database. error: %s", err.Error())
+	}
+	return nil
+}
+
+// ValidateSignalParts checks each part of the signal against the signal schema of the model. The error lists
+// all the parts that are not valid
+func (m *Model) ValidateSignalParts(s string) error {
+	if m.IsPopular() || len(m.SignalSchema) == 0 {
+		return nil
+	}
+	parts := []string{s}
+	if m.Concatenator != "" {
+		parts = strings.Split(s, m.Concatenator)
+	}
+	if len(parts) != len(m.SignalOrder) {
+		return fmt.Errorf("the expected signal format must be %s", strings.Join(m.SignalOrder, m.Concatenator))
+	}
+	var msgs []string
+	for i, name := range m.SignalOrder {
+		if err := m.validateSignalPart(name, parts[i]); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("%s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// validateSignalPart checks a single part of the signal. Parts without schema accept any value
+func (m *Model) validateSignalPart(name, v string) error {
+	p, ok := m.SignalSchema[name]
+	if !ok {
+		return nil
+	}
+	if err := p.Check(v); err != nil {
+		return fmt.Errorf("signal part %s %s", name, err.Error())
+	}
+	return nil
+}
+
+// compilePattern returns the compiled pattern from the cache or compiles it
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := signalPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("signal part pattern %s is not valid. error: %s", pattern, err.Error())
+	}
+	signalPatterns.Store(pattern, re)
+	return re, nil
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSignalParts(t *testing.T) {
+	m := Model{
+		Name:         "test",
+		SignalOrder:  []string{"userId", "sessionId", "device", "country"},
+		Concatenator: "|",
+		SignalSchema: map[string]SignalPart{
+			"userId":    {Type: SignalPartInteger},
+			"sessionId": {Type: SignalPartUUID},
+			"device":    {Type: SignalPartEnum, Values: []string{"tv", "web"}},
+			"country":   {Type: SignalPartRegex, Pattern: "^[a-z]{2}$"},
+		},
+	}
+
+	tests := map[string]struct {
+		input    string
+		expected string
+	}{
+		"correct": {
+			input:    "123|b8e1bd3c-1f5c-4d5c-a3ee-8f6c1e4a0a9b|tv|nl",
+			expected: "",
+		},
+		"wrong integer": {
+			input:    "abc|b8e1bd3c-1f5c-4d5c-a3ee-8f6c1e4a0a9b|tv|nl",
+			expected: "signal part userId must be an integer, got abc",
+		},
+		"wrong parts": {
+			input:    "123|not-a-uuid|radio|nld",
+			expected: "signal part sessionId must be a UUID, got not-a-uuid; signal part device must be one of [tv web], got radio; signal part country must match ^[a-z]{2}$, got nld",
+		},
+		"wrong format": {
+			input:    "123|tv",
+			expected: "the expected signal format must be userId|sessionId|device|country",
+		},
+	}
+	for testName, test := range tests {
+		t.Logf("Running test case %s", testName)
+		err := m.ValidateSignalParts(test.input)
+		if test.expected == "" {
+			assert.Nil(t, err)
+			continue
+		}
+		assert.Equal(t, test.expected, err.Error())
+	}
+}
+
+func TestSetSignalSchema(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	m, err := NewModel("schema", "_", []string{"userId", "device"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+
+	err = m.SetSignalSchema(map[string]SignalPart{"articleId": {Type: SignalPartInteger}}, dbc)
+	assert.Equal(t, "signal part articleId is not in the signalOrder [userId device]", err.Error())
+
+	err = m.SetSignalSchema(map[string]SignalPart{"userId": {Type: "float"}}, dbc)
+	assert.Equal(t, "signal part type float not supported. use one of [string integer uuid enum regex]", err.Error())
+
+	err = m.SetSignalSchema(map[string]SignalPart{"device": {Type: SignalPartEnum}}, dbc)
+	assert.Equal(t, "signal part type enum requires at least one value", err.Error())
+
+	err = m.SetSignalSchema(map[string]SignalPart{"device": {Type: SignalPartRegex, Pattern: "("}}, dbc)
+	assert.NotNil(t, err)
+
+	if err := m.SetSignalSchema(map[string]SignalPart{"userId": {Type: SignalPartInteger}}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err := GetModel("schema", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, map[string]SignalPart{"userId": {Type: SignalPartInteger}}, stored.SignalSchema)
+
+	// the schema of the parts removed from the signalOrder is dropped
+	if err := stored.UpdateSignalFormat([]string{"device"}, "_", false, dbc); err != nil {
+		t.FailNow()
+	}
+	assert.Nil(t, stored.SignalSchema)
+}
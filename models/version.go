@@ -0,0 +1,125 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rtlnl/phoenix/pkg/db"
+)
+
+const (
+	// format of the tables holding a specific version of the data of a model
+	versionTableFormat = "%s@%s"
+)
+
+// ModelVersion contains the information of a single upload of data in batch
+type ModelVersion struct {
+	BatchID   string    `json:"batchId" description:"ID of the batch upload that created the version"`
+	Timestamp time.Time `json:"timestamp" description:"time when the version has been uploaded"`
+	Lines     int       `json:"lines" description:"total count of lines in the uploaded file"`
+	Errors    int       `json:"errors" description:"total count of lines that could not be uploaded"`
+}
+
+// DataTable returns the name of the table holding the data currently served by the model. Models that never
+// received a versioned batch upload store the data in the table named after the model itself
+func (m *Model) DataTable() string {
+	return m.VersionTable(m.Version)
+}
+
+// VersionTable returns the name of the table holding a specific version of the data of the model
+func (m *Model) VersionTable(version string) string {
+	if version == "" {
+		return m.Name
+	}
+	return fmt.Sprintf(versionTableFormat, m.Name, version)
+}
+
+// AddVersion records a new version in the history of the model and atomically switches the model to it
+func (m *Model) AddVersion(v ModelVersion, dbc db.DB) error {
+	// reload the model to not override changes made during the upload
+	stored, err := GetModel(m.Name, dbc)
+	if err != nil {
+		return err
+	}
+	// the data of an unversioned model is not part of the history, hence it is removed
+	// as soon as the first version is activated
+	unversioned := stored.Version == ""
+
+	stored.Versions = append(stored.Versions, v)
+	stored.Version = v.BatchID
+	if _, err := storeModel(stored, dbc); err != nil {
+		return fmt.Errorf("error in activating version %s. error: %s", v.BatchID, err.Error())
+	}
+	*m = stored
+	m.InvalidateCache(dbc)
+
+	if unversioned {
+		if err := dbc.DropTable(m.Name); err != nil {
+			return fmt.Errorf("error in deleting the unversioned data of the model. error: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// ActivateVersion re-points the model to a version of its history
+func (m *Model) ActivateVersion(batchID string, dbc db.DB) error {
+	if m.versionIndex(batchID) < 0 {
+		return fmt.Errorf("version %s not found in model %s", batchID, m.Name)
+	}
+	m.Version = batchID
+	if _, err := storeModel(*m, dbc); err != nil {
+		return fmt.Errorf("error in activating version %s. error: %s", batchID, err.Error())
+	}
+	m.InvalidateCache(dbc)
+	return nil
+}
+
+// Rollback re-points the model to the version uploaded before the current one
+func (m *Model) Rollback(dbc db.DB) error {
+	i := m.versionIndex(m.Version)
+	if i < 1 {
+		return fmt.Errorf("model with name %s has no previous version", m.Name)
+	}
+	return m.ActivateVersion(m.Versions[i-1].BatchID, dbc)
+}
+
+// DeleteOldVersions removes the data of the versions that exceed the retention count, starting from the oldest
+// ones. The version currently served is never removed. It returns the versions that have been removed
+func (m *Model) DeleteOldVersions(retention int, dbc db.DB) ([]ModelVersion, error) {
+	if retention < 1 {
+		return nil, fmt.Errorf("retention must be at least 1")
+	}
+
+	var kept, removed []ModelVersion
+	exceeding := len(m.Versions) - retention
+	for _, v := range m.Versions {
+		if exceeding > 0 && v.BatchID != m.Version {
+			removed = append(removed, v)
+			exceeding--
+			continue
+		}
+		kept = append(kept, v)
+	}
+
+	// update the history first so that the removed versions cannot be activated anymore
+	m.Versions = kept
+	if _, err := storeModel(*m, dbc); err != nil {
+		return nil, fmt.Errorf("error in updating the versions of the model. error: %s", err.Error())
+	}
+	for _, v := range removed {
+		if err := dbc.DropTable(m.VersionTable(v.BatchID)); err != nil {
+			return nil, fmt.Errorf("error in deleting the data of version %s. error: %s", v.BatchID, err.Error())
+		}
+	}
+	return removed, nil
+}
+
+// versionIndex returns the position of the version in the history of the model or -1 if not found
+func (m *Model) versionIndex(batchID string) int {
+	for i, v := range m.Versions {
+		if v.BatchID == batchID {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddVersion(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	m, err := NewModel("versioned", "", []string{"a"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, "versioned", m.DataTable())
+
+	// data uploaded before the model was versioned
+	if err := dbc.AddOne(m.DataTable(), "1", "[]"); err != nil {
+		t.FailNow()
+	}
+
+	if err := dbc.AddOne(m.VersionTable("v1"), "1", "[]"); err != nil {
+		t.FailNow()
+	}
+	if err := m.AddVersion(ModelVersion{BatchID: "v1", Timestamp: time.Now(), Lines: 10, Errors: 1}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	assert.Equal(t, "v1", m.Version)
+	assert.Equal(t, 1, len(m.Versions))
+	assert.Equal(t, 10, m.Versions[0].Lines)
+	assert.Equal(t, 1, m.Versions[0].Errors)
+	assert.Equal(t, "versioned@v1", m.DataTable())
+
+	// the unversioned data is removed
+	_, err = dbc.GetOne("versioned", "1")
+	assert.NotNil(t, err)
+
+	if err := dbc.AddOne(m.VersionTable("v2"), "1", "[]"); err != nil {
+		t.FailNow()
+	}
+	if err := m.AddVersion(ModelVersion{BatchID: "v2", Timestamp: time.Now()}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err := GetModel("versioned", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, "v2", stored.Version)
+	assert.Equal(t, 2, len(stored.Versions))
+
+	if err := stored.Rollback(dbc); err != nil {
+		t.FailNow()
+	}
+
+	stored, err = GetModel("versioned", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, "v1", stored.Version)
+
+	// the data of the previous version is still there
+	_, err = dbc.GetOne(stored.DataTable(), "1")
+	assert.Nil(t, err)
+
+	// nothing comes before the first version
+	err = stored.Rollback(dbc)
+	assert.Equal(t, "model with name versioned has no previous version", err.Error())
+}
+
+func TestActivateVersion(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	m, err := NewModel("activated", "", []string{"a"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	for _, v := range []string{"v1", "v2", "v3"} {
+		if err := m.AddVersion(ModelVersion{BatchID: v, Timestamp: time.Now()}, dbc); err != nil {
+			t.FailNow()
+		}
+	}
+
+	if err := m.ActivateVersion("v1", dbc); err != nil {
+		t.FailNow()
+	}
+	stored, err := GetModel("activated", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, "v1", stored.Version)
+
+	err = m.ActivateVersion("v4", dbc)
+	assert.Equal(t, "version v4 not found in model activated", err.Error())
+}
+
+func TestRollbackWithoutPreviousVersion(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	m, err := NewModel("unversioned", "", []string{"a"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	err = m.Rollback(dbc)
+	assert.Equal(t, "model with name unversioned has no previous version", err.Error())
+}
+
+func TestDeleteOldVersions(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	m, err := NewModel("retention", "", []string{"a"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	for _, v := range []string{"v1", "v2", "v3", "v4"} {
+		if err := dbc.AddOne(m.VersionTable(v), "1", "[]"); err != nil {
+			t.FailNow()
+		}
+		if err := m.AddVersion(ModelVersion{BatchID: v, Timestamp: time.Now()}, dbc); err != nil {
+			t.FailNow()
+		}
+	}
+	// the active version is never removed
+	if err := m.ActivateVersion("v1", dbc); err != nil {
+		t.FailNow()
+	}
+
+	removed, err := m.DeleteOldVersions(2, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, 2, len(removed))
+	assert.Equal(t, "v2", removed[0].BatchID)
+	assert.Equal(t, "v3", removed[1].BatchID)
+
+	stored, err := GetModel("retention", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, "v1", stored.Version)
+	assert.Equal(t, 2, len(stored.Versions))
+	assert.Equal(t, "v4", stored.Versions[1].BatchID)
+
+	_, err = dbc.GetOne(m.VersionTable("v2"), "1")
+	assert.NotNil(t, err)
+	_, err = dbc.GetOne(m.VersionTable("v4"), "1")
+	assert.Nil(t, err)
+
+	_, err = m.DeleteOldVersions(0, dbc)
+	assert.Equal(t, "retention must be at least 1", err.Error())
+}
@@ -3,12 +3,14 @@ package batch
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
@@ -27,6 +29,8 @@ const (
 	BulkQueued = "QUEUED"
 	// BulkUploading represents the uploading status
 	BulkUploading = "UPLOADING"
+	// BulkRunning represents the status of a batch operation other than the upload in progress
+	BulkRunning = "RUNNING"
 	// BulkSucceeded represents the succeeded status
 	BulkSucceeded = "SUCCEEDED"
 	// BulkPartialUpload represent the partial upload status
@@ -37,6 +41,12 @@ const (
 	TableBulkStatus = "bulkStatus"
 	// TableBulkErrors is the name of the error tables for storing all the errors of a specific batch
 	TableBulkErrors = "bulkErrors"
+	// TableRemovalStatus is the name of the table for storing the progress of the item removals
+	TableRemovalStatus = "removalStatus"
+	// number of signals checked before storing the progress of an item removal
+	removalProgressInterval = 1000
+	// number of attempts of removing an item from a signal that keeps being changed concurrently
+	maxRemovalRetries = 10
 	// max number of Errors that will be stored in DB
 	maxErrorLines = 50
 )
@@ -59,11 +69,24 @@ type DataUploadedError struct {
 	Errors              []models.LineError `json:"error" description:"errors found"`
 }
 
+// RemovalProgress contains the progress of the removal of an item from all the signals of a model
+type RemovalProgress struct {
+	Status    string `json:"status" description:"status of the removal"`
+	ModelName string `json:"modelName" description:"name of the model the item is removed from"`
+	ItemID    string `json:"itemId" description:"ID of the item to remove"`
+	Scanned   int    `json:"scanned" description:"number of signals checked so far"`
+	Updated   int    `json:"updated" description:"number of signals the item has been removed from so far"`
+}
+
 // Operator is the object responsible for uploading data in batch to Database
 type Operator struct {
 	DBClient    db.DB
 	CacheClient cache.Cache
 	Model       models.Model
+	// set when at least one of the pipelines failed during the upload
+	failed int32
+	// count of lines read from the file during the upload
+	lines int32
 }
 
 // NewOperator returns the object responsible for uploading the data in batch to Database
@@ -74,7 +97,8 @@ func NewOperator(dbc db.DB, m models.Model) *Operator {
 	}
 }
 
-// UploadDataFromFile reads from a file and upload line-by-line to Database on a particular BatchID
+// UploadDataFromFile reads from a file and upload line-by-line to Database on a particular BatchID. The data is
+// written in a new version of the model that is activated only once all the lines are uploaded
 func (o *Operator) UploadDataFromFile(file *io.ReadCloser, batchID string) error {
 	start := time.Now()
 
@@ -86,26 +110,22 @@ func (o *Operator) UploadDataFromFile(file *io.ReadCloser, batchID string) error
 	rd := bufio.NewReader(*file)
 	rs := make(chan *models.RecordQueue)
 	le := make(chan models.LineError)
+	table := o.Model.VersionTable(batchID)
 
 	// create sync group
 	wg := &sync.WaitGroup{}
 
 	// fillup the channel with lines
 	go func() {
-		o.IterateFile(rd, o.Model.Name, rs, le)
+		o.IterateFile(rd, table, rs, le)
 		close(rs)
 		close(le)
 	}()
 
 	// store eventual errors
+	ne := make(chan int, 1)
 	go func() {
-		if o.StoreErrors(batchID, le) > 0 {
-			// write to DB that it partially uploaded the data
-			o.SetStatus(batchID, BulkPartialUpload)
-		} else {
-			// write to DB that it succeeded
-			o.SetStatus(batchID, BulkSucceeded)
-		}
+		ne <- o.StoreErrors(batchID, le)
 	}()
 
 	// consumes all the lines in parallel based on number of cpus
@@ -119,19 +139,48 @@ func (o *Operator) UploadDataFromFile(file *io.ReadCloser, batchID string) error
 
 	// wait until done
 	wg.Wait()
+	numErrors := <-ne
 
 	elapsed := time.Since(start)
 	log.Info().Str("BATCH", fmt.Sprintf("upload in %s", elapsed))
 
-	return nil
+	// discard the incomplete version
+	if atomic.LoadInt32(&o.failed) == 1 {
+		if err := o.DBClient.DropTable(table); err != nil {
+			log.Error().Msg(err.Error())
+		}
+		return fmt.Errorf("upload of batchId %s failed", batchID)
+	}
+
+	// switch the model to the new version
+	v := models.ModelVersion{
+		BatchID:   batchID,
+		Timestamp: start,
+		Lines:     int(atomic.LoadInt32(&o.lines)),
+		Errors:    numErrors,
+	}
+	if err := o.Model.AddVersion(v, o.DBClient); err != nil {
+		return err
+	}
+
+	if numErrors > 0 {
+		// write to DB that it partially uploaded the data
+		return o.SetStatus(batchID, BulkPartialUpload)
+	}
+	// write to DB that it succeeded
+	return o.SetStatus(batchID, BulkSucceeded)
 }
 
-// UploadDataDirectly does an insert directly to Database
-func (o *Operator) UploadDataDirectly(bd []Data) (string, DataUploadedError, error) {
+// UploadDataDirectly does an insert directly to Database. When the expiry is set all the signals stop being served
+// after it
+func (o *Operator) UploadDataDirectly(bd []Data, expiresAt *time.Time) (string, DataUploadedError, error) {
 	var ln, ne int = 0, 0
 	var vl bool = false
 	var lineErrors []models.LineError
 
+	// the public instances must not serve the cached recommendations of the uploaded signals
+	defer o.Model.InvalidateCache(o.DBClient)
+
 	// check upfront if signal validation is required
 	if o.Model.RequireSignalFormat() {
 		vl = true
@@ -150,13 +199,40 @@ func (o *Operator) UploadDataDirectly(bd []Data) (string, DataUploadedError, err
 				}
 				continue
 			}
+			if err := o.Model.ValidateSignalParts(sig); err != nil {
+				ne++
+				if ln <= maxErrorLines {
+					lineErrors = append(lineErrors, models.LineError{strconv.Itoa(ln): err.Error()})
+				}
+				continue
+			}
+			if err := o.Model.ValidateReservedSignal(sig); err != nil {
+				ne++
+				if ln <= maxErrorLines {
+					lineErrors = append(lineErrors, models.LineError{strconv.Itoa(ln): err.Error()})
+				}
+				continue
+			}
+			if err := o.Model.ItemSchema.Validate(recommendedItems); err != nil {
+				ne++
+				if ln <= maxErrorLines {
+					lineErrors = append(lineErrors, models.LineError{strconv.Itoa(ln): err.Error()})
+				}
+				continue
+			}
+			if o.Model.SortOnUpload {
+				if err := models.ValidateScores(recommendedItems); err != nil {
+					ne++
+					if ln <= maxErrorLines {
+						lineErrors = append(lineErrors, models.LineError{strconv.Itoa(ln): err.Error()})
+					}
+					continue
+				}
+				recommendedItems = models.SortByScore(recommendedItems)
+			}
 
 			// upload to DB
-			ser, err := utils.SerializeObject(recommendedItems)
-			if err != nil {
-				log.Error().Msgf("could not serialize recommended object. error: %s", err.Error())
-			}
-			if err := o.DBClient.AddOne(o.Model.Name, sig, ser); err != nil {
+			if _, err := o.Model.StoreRecommendations(sig, recommendedItems, expiresAt, o.DBClient); err != nil {
 				return "", DataUploadedError{}, err
 			}
 		}
@@ -181,6 +257,7 @@ func (o *Operator) IterateFile(rd *bufio.Reader, setName string, rs chan<- *mode
 		if err == io.EOF {
 			break
 		}
+		atomic.AddInt32(&o.lines, 1)
 
 		// string new-line character
 		l := strings.TrimSuffix(line, "\n")
@@ -206,6 +283,49 @@ func (o *Operator) IterateFile(rd *bufio.Reader, setName string, rs chan<- *mode
 			log.Warn().Str("READ", "signal not formatted correctly").Str("SIGNAL", entry.SignalID).Str("LINE", line)
 			continue
 		}
+		if err := o.Model.ValidateSignalParts(entry.SignalID); err != nil {
+			le <- models.LineError{
+				"line":    strconv.Itoa(ln),
+				"message": err.Error(),
+			}
+			log.Warn().Str("READ", err.Error()).Str("SIGNAL", entry.SignalID).Str("LINE", line)
+			continue
+		}
+		if err := o.Model.ValidateReservedSignal(entry.SignalID); err != nil {
+			le <- models.LineError{
+				"line":    strconv.Itoa(ln),
+				"message": err.Error(),
+			}
+			log.Warn().Str("READ", err.Error()).Str("SIGNAL", entry.SignalID).Str("LINE", line)
+			continue
+		}
+		if err := o.Model.ItemSchema.Validate(entry.Recommended); err != nil {
+			le <- models.LineError{
+				"line":    strconv.Itoa(ln),
+				"message": err.Error(),
+			}
+			log.Warn().Str("READ", err.Error()).Str("SIGNAL", entry.SignalID).Str("LINE", line)
+			continue
+		}
+		if models.IsExpired(entry.ExpiresAt) {
+			le <- models.LineError{
+				"line":    strconv.Itoa(ln),
+				"message": models.ValidateExpiry(entry.ExpiresAt).Error(),
+			}
+			log.Warn().Str("READ", "signal already expired").Str("SIGNAL", entry.SignalID).Str("LINE", line)
+			continue
+		}
+		if o.Model.SortOnUpload {
+			if err := models.ValidateScores(entry.Recommended); err != nil {
+				le <- models.LineError{
+					"line":    strconv.Itoa(ln),
+					"message": err.Error(),
+				}
+				log.Warn().Str("READ", err.Error()).Str("SIGNAL", entry.SignalID).Str("LINE", line)
+				continue
+			}
+			entry.Recommended = models.SortByScore(entry.Recommended)
+		}
 		// add to channel
 		rs <- &models.RecordQueue{Table: setName, Entry: entry, Error: nil}
 	}
@@ -253,12 +373,15 @@ Loop:
 			}
 
 			// received message, continuing
-			ser, err := utils.SerializeObject(r.Entry.Recommended)
+			ser, err := models.SerializeEntry(r.Entry.Recommended, r.Entry.ExpiresAt)
 			if err != nil {
 				log.Error().Msgf("cold not serialize recommendations. error: %s", err.Error())
 				continue
 			}
 			o.DBClient.PipelineAddOne(r.Table, r.Entry.SignalID, ser)
+			if r.Entry.ExpiresAt != nil {
+				o.DBClient.PipelineSetExpiry(r.Table, r.Entry.SignalID, *r.Entry.ExpiresAt)
+			}
 			log.Info().Str("INSERT", fmt.Sprintf("signalId %s", r.Entry.SignalID)).Str("MODEL", o.Model.Name)
 
 			// append to buffer
@@ -279,6 +402,7 @@ Loop:
 // flushPipeline executes the pipeline
 func (o *Operator) flushPipeline(batchID string) {
 	if err := o.DBClient.PipelineExec(); err != nil {
+		atomic.StoreInt32(&o.failed, 1)
 		log.Error().Msg(err.Error())
 		// write to DB that it failed
 		if err := o.DBClient.AddOne(TableBulkStatus, batchID, BulkFailed); err != nil {
@@ -288,17 +412,17 @@ func (o *Operator) flushPipeline(batchID string) {
 	}
 }
 
-// StoreErrors stores the errors in Database from the channel in input
+// StoreErrors stores the errors in Database from the channel in input. It returns the total count of errors
+// received, including the ones exceeding the stored limit
 func (o *Operator) StoreErrors(batchID string, le chan models.LineError) int {
 	allErrors := []models.LineError{}
 	i := 0
 	for lineError := range le {
+		// keep consuming the channel to not block the producer
 		if i < maxErrorLines {
 			allErrors = append(allErrors, lineError)
-			i++
-			continue
 		}
-		break
+		i++
 	}
 	// save to DB the errors list if any
 	if len(allErrors) > 0 {
@@ -313,6 +437,113 @@ func (o *Operator) StoreErrors(batchID string, le chan models.LineError) int {
 	return i
 }
 
+// RemoveItem removes the item from the recommendations of every signal of the model. Only the data currently
+// served by the model is changed. The progress is stored in TableRemovalStatus under the jobID
+func (o *Operator) RemoveItem(jobID, itemID string) error {
+	table := o.Model.DataTable()
+	p := RemovalProgress{
+		Status:    BulkRunning,
+		ModelName: o.Model.Name,
+		ItemID:    itemID,
+	}
+	if err := o.SetRemovalProgress(jobID, p); err != nil {
+		return err
+	}
+
+	err := o.DBClient.IterateRecords(table, func(signalID, value string) error {
+		p.Scanned++
+		if p.Scanned%removalProgressInterval == 0 {
+			if err := o.SetRemovalProgress(jobID, p); err != nil {
+				return err
+			}
+		}
+
+		updated, err := o.removeItemFromSignal(table, signalID, value, itemID)
+		if updated {
+			p.Updated++
+		}
+		return err
+	})
+	// some signals might have been updated even if the removal failed
+	o.Model.InvalidateCache(o.DBClient)
+	if err != nil {
+		p.Status = BulkFailed
+		if err := o.SetRemovalProgress(jobID, p); err != nil {
+			log.Error().Msg(err.Error())
+		}
+		return fmt.Errorf("removal of item %s from model %s failed. error: %s", itemID, o.Model.Name, err.Error())
+	}
+
+	p.Status = BulkSucceeded
+	return o.SetRemovalProgress(jobID, p)
+}
+
+// removeItemFromSignal removes the item from the recommendations of the signal. The value is written only if the
+// signal did not change since it has been read, otherwise the signal is read again and the removal retried, so
+// that the concurrent updates of the streaming API are not overwritten. It returns whether the signal changed
+func (o *Operator) removeItemFromSignal(table, signalID, value, itemID string) (bool, error) {
+	for i := 0; i < maxRemovalRetries; i++ {
+		items, expiresAt, err := models.DeserializeEntry(value)
+		if err != nil {
+			log.Warn().Str("SIGNAL", signalID).Str("MODEL", o.Model.Name).Msgf("REMOVE could not deserialize recommendations. error: %s", err.Error())
+			return false, nil
+		}
+
+		// keep all the items but the removed one
+		kept := make([]models.ItemScore, 0, len(items))
+		for _, is := range items {
+			if is["item"] != itemID {
+				kept = append(kept, is)
+			}
+		}
+		if len(kept) == len(items) {
+			return false, nil
+		}
+
+		// the expiry of the signal does not change
+		ser, err := models.SerializeEntry(kept, expiresAt)
+		if err != nil {
+			return false, err
+		}
+		swapped, err := o.DBClient.CompareAndSwap(table, signalID, db.Checksum(value), ser, expiresAt)
+		if err != nil || swapped {
+			return swapped, err
+		}
+
+		// the signal changed in the meantime
+		value, err = o.DBClient.GetOne(table, signalID)
+		if errors.Is(err, db.ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return false, fmt.Errorf("signal %s kept changing while removing the item", signalID)
+}
+
+// SetRemovalProgress stores the progress of the removal of an item in the DB
+func (o *Operator) SetRemovalProgress(jobID string, p RemovalProgress) error {
+	ser, err := utils.SerializeObject(p)
+	if err != nil {
+		return fmt.Errorf("could not serialize removal progress. error: %s", err.Error())
+	}
+	return o.DBClient.AddOne(TableRemovalStatus, jobID, ser)
+}
+
+// GetRemovalProgress returns the progress of the removal of an item
+func GetRemovalProgress(jobID string, dbc db.DB) (RemovalProgress, error) {
+	var p RemovalProgress
+	ser, err := dbc.GetOne(TableRemovalStatus, jobID)
+	if err != nil {
+		return p, fmt.Errorf("removal job with ID %s not found", jobID)
+	}
+	if err := json.Unmarshal([]byte(ser), &p); err != nil {
+		return p, fmt.Errorf("could not deserialize removal progress. error: %s", err.Error())
+	}
+	return p, nil
+}
+
 // SetStatus sets the status in the DB. The error message is logged only
 func (o *Operator) SetStatus(batchID, status string) error {
 	err := o.DBClient.AddOne(TableBulkStatus, batchID, status)
@@ -2,7 +2,238 @@ package batch
 
 import (
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/db"
+	"github.com/rtlnl/phoenix/utils"
+)
+
+var (
+	testDBHost     = utils.GetEnv("DB_HOST", "127.0.0.1:6379")
+	testDBPassword = utils.GetEnv("DB_PASSWORD", "")
 )
 
 func TestNewOperator(t *testing.T) {
 }
+
+func TestRemoveItem(t *testing.T) {
+	dbc, err := db.NewRedisClient(testDBHost, db.Password(testDBPassword))
+	if err != nil {
+		t.FailNow()
+	}
+	defer dbc.Close()
+
+	m, err := models.NewModel("removal", "", []string{"userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+
+	if err := dbc.AddOne(m.DataTable(), "1", `[{"item":"a"},{"item":"b"}]`); err != nil {
+		t.FailNow()
+	}
+	if err := dbc.AddOne(m.DataTable(), "2", `[{"item":"c"}]`); err != nil {
+		t.FailNow()
+	}
+	if err := dbc.AddOne(m.DataTable(), "3", `[{"item":"b"},{"item":"c"}]`); err != nil {
+		t.FailNow()
+	}
+
+	o := NewOperator(dbc, m)
+	if err := o.RemoveItem("job", "b"); err != nil {
+		t.FailNow()
+	}
+
+	val, err := dbc.GetOne(m.DataTable(), "1")
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"item":"a"}]`, val)
+	val, err = dbc.GetOne(m.DataTable(), "3")
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"item":"c"}]`, val)
+
+	p, err := GetRemovalProgress("job", dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, RemovalProgress{Status: BulkSucceeded, ModelName: "removal", ItemID: "b", Scanned: 3, Updated: 2}, p)
+
+	_, err = GetRemovalProgress("missing", dbc)
+	assert.Equal(t, "removal job with ID missing not found", err.Error())
+}
+
+func TestRemoveItemConcurrentUpdate(t *testing.T) {
+	dbc, err := db.NewRedisClient(testDBHost, db.Password(testDBPassword))
+	if err != nil {
+		t.FailNow()
+	}
+	defer dbc.Close()
+
+	m, err := models.NewModel("concurrentremoval", "", []string{"userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+
+	// the signal has been updated after being scanned
+	if err := dbc.AddOne(m.DataTable(), "1", `[{"item":"a"},{"item":"b"},{"item":"d"}]`); err != nil {
+		t.FailNow()
+	}
+
+	o := NewOperator(dbc, m)
+	updated, err := o.removeItemFromSignal(m.DataTable(), "1", `[{"item":"a"},{"item":"b"}]`, "b")
+	assert.Nil(t, err)
+	assert.True(t, updated)
+
+	val, err := dbc.GetOne(m.DataTable(), "1")
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"item":"a"},{"item":"d"}]`, val)
+
+	// the signal has been deleted after being scanned
+	updated, err = o.removeItemFromSignal(m.DataTable(), "2", `[{"item":"b"}]`, "b")
+	assert.Nil(t, err)
+	assert.False(t, updated)
+}
+
+func TestUploadDataDirectlySignalSchema(t *testing.T) {
+	dbc, err := db.NewRedisClient(testDBHost, db.Password(testDBPassword))
+	if err != nil {
+		t.FailNow()
+	}
+	defer dbc.Close()
+
+	m, err := models.NewModel("typed", "_", []string{"userId", "device"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+
+	if err := m.SetSignalSchema(map[string]models.SignalPart{"userId": {Type: models.SignalPartInteger}}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	o := NewOperator(dbc, m)
+	ln, due, err := o.UploadDataDirectly([]Data{
+		{"1_tv": []models.ItemScore{{"item": "a"}}},
+		{"abc_tv": []models.ItemScore{{"item": "b"}}},
+	}, nil)
+	if err != nil {
+		t.FailNow()
+	}
+
+	assert.Equal(t, "2", ln)
+	assert.Equal(t, "1", due.NumberOfLinesFailed)
+	assert.Equal(t, []models.LineError{{"2": "signal part userId must be an integer, got abc"}}, due.Errors)
+
+	_, err = dbc.GetOne(m.DataTable(), "abc_tv")
+	assert.NotNil(t, err)
+}
+
+func TestUploadDataDirectlyItemSchema(t *testing.T) {
+	dbc, err := db.NewRedisClient(testDBHost, db.Password(testDBPassword))
+	if err != nil {
+		t.FailNow()
+	}
+	defer dbc.Close()
+
+	m, err := models.NewModel("items", "", []string{"userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+
+	if err := m.SetItemSchema(models.ItemSchema{Types: []string{"movie"}}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	o := NewOperator(dbc, m)
+	_, due, err := o.UploadDataDirectly([]Data{
+		{"1": []models.ItemScore{{"item": "a", "type": "movie"}}},
+		{"2": []models.ItemScore{{"item": "b", "type": "clip"}}},
+	}, nil)
+	if err != nil {
+		t.FailNow()
+	}
+
+	assert.Equal(t, "1", due.NumberOfLinesFailed)
+	assert.Equal(t, []models.LineError{{"2": "item 1 has type clip. allowed types are [movie]"}}, due.Errors)
+}
+
+func TestUploadDataDirectlySortOnUpload(t *testing.T) {
+	dbc, err := db.NewRedisClient(testDBHost, db.Password(testDBPassword))
+	if err != nil {
+		t.FailNow()
+	}
+	defer dbc.Close()
+
+	m, err := models.NewModel("sorted", "", []string{"userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+
+	if err := m.SetSortOnUpload(true, dbc); err != nil {
+		t.FailNow()
+	}
+
+	o := NewOperator(dbc, m)
+	_, due, err := o.UploadDataDirectly([]Data{
+		{"1": []models.ItemScore{{"item": "a", "score": "0.1"}, {"item": "b", "score": "0.7"}}},
+		{"2": []models.ItemScore{{"item": "c", "score": "high"}}},
+	}, nil)
+	if err != nil {
+		t.FailNow()
+	}
+
+	assert.Equal(t, []models.LineError{{"2": "item 1 has score high that is not a number"}}, due.Errors)
+
+	val, err := dbc.GetOne(m.DataTable(), "1")
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"item":"b","score":"0.7"},{"item":"a","score":"0.1"}]`, val)
+}
+
+func TestUploadDataDirectlyExpiry(t *testing.T) {
+	dbc, err := db.NewRedisClient(testDBHost, db.Password(testDBPassword))
+	if err != nil {
+		t.FailNow()
+	}
+	defer dbc.Close()
+
+	m, err := models.NewModel("expiring", "", []string{"userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer m.DeleteModel(dbc)
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	o := NewOperator(dbc, m)
+	_, due, err := o.UploadDataDirectly([]Data{
+		{"1": []models.ItemScore{{"item": "a"}}},
+	}, &expiresAt)
+	if err != nil {
+		t.FailNow()
+	}
+	assert.Equal(t, "0", due.NumberOfLinesFailed)
+
+	val, err := dbc.GetOne(m.DataTable(), "1")
+	assert.Nil(t, err)
+
+	items, exp, err := models.DeserializeEntry(val)
+	assert.Nil(t, err)
+	assert.Equal(t, []models.ItemScore{{"item": "a"}}, items)
+	assert.True(t, expiresAt.Equal(*exp))
+
+	// nothing is deleted before the expiry
+	n, err := m.DeleteExpiredSignals(time.Now(), dbc)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = m.DeleteExpiredSignals(expiresAt.Add(time.Second), dbc)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = dbc.GetOne(m.DataTable(), "1")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,122 @@
+package blocklist
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/db"
+)
+
+// Blocklist is the in-memory copy of the global blocklist. It is kept in sync with the database by listening
+// to the changes published on models.BlocklistChannel
+type Blocklist struct {
+	dbc   db.DB
+	mu    sync.RWMutex
+	items map[string]struct{}
+}
+
+// NewBlocklist returns a new Blocklist object loaded with the items currently blocked
+func NewBlocklist(dbc db.DB) (*Blocklist, error) {
+	b := &Blocklist{
+		dbc:   dbc,
+		items: map[string]struct{}{},
+	}
+	if err := b.Reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Reload replaces the in-memory items with the ones stored in the database
+func (b *Blocklist) Reload() error {
+	blocked, err := models.GetBlockedItems(b.dbc)
+	if err != nil {
+		return err
+	}
+	items := make(map[string]struct{}, len(blocked))
+	for _, item := range blocked {
+		items[item] = struct{}{}
+	}
+
+	b.mu.Lock()
+	b.items = items
+	b.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the items every time a change is published. Since published messages are lost while the
+// subscription is reconnecting, the items are reloaded every refresh interval as well. It returns the function
+// for stopping the watcher
+func (b *Blocklist) Watch(refresh time.Duration) (func() error, error) {
+	msgs, unsubscribe, err := b.dbc.Subscribe(models.BlocklistChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		ticker := time.NewTicker(refresh)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case _, ok := <-msgs:
+				// subscription closed
+				if !ok {
+					return
+				}
+			case <-ticker.C:
+			}
+			if err := b.Reload(); err != nil {
+				log.Error().Msgf("could not reload the blocklist. error: %s", err.Error())
+			}
+		}
+	}()
+	return unsubscribe, nil
+}
+
+// Contains checks if the item is blocked
+func (b *Blocklist) Contains(item string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, ok := b.items[item]
+	return ok
+}
+
+// IsEmpty checks if no item is blocked
+func (b *Blocklist) IsEmpty() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.items) == 0
+}
+
+// Filter returns the items that are not blocked keeping their order
+func (b *Blocklist) Filter(items []models.ItemScore) []models.ItemScore {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.items) == 0 {
+		return items
+	}
+
+	var filtered []models.ItemScore
+	for i, is := range items {
+		if _, ok := b.items[is["item"]]; !ok {
+			if filtered != nil {
+				filtered = append(filtered, is)
+			}
+			continue
+		}
+		// first blocked item, copy the ones already checked
+		if filtered == nil {
+			filtered = make([]models.ItemScore, i, len(items))
+			copy(filtered, items[:i])
+		}
+	}
+	if filtered == nil {
+		return items
+	}
+	return filtered
+}
@@ -0,0 +1,79 @@
+package blocklist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/db"
+	"github.com/rtlnl/phoenix/utils"
+)
+
+var (
+	testDBHost     = utils.GetEnv("DB_HOST", "127.0.0.1:6379")
+	testDBPassword = utils.GetEnv("DB_PASSWORD", "")
+)
+
+func TestFilter(t *testing.T) {
+	dbc, err := db.NewRedisClient(testDBHost, db.Password(testDBPassword))
+	if err != nil {
+		t.FailNow()
+	}
+	defer dbc.Close()
+
+	if err := models.BlockItems([]string{"2", "4"}, dbc); err != nil {
+		t.FailNow()
+	}
+	defer models.UnblockItems([]string{"2", "4"}, dbc)
+
+	b, err := NewBlocklist(dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	items := []models.ItemScore{{"item": "1"}, {"item": "2"}, {"item": "3"}, {"item": "4"}}
+	filtered := b.Filter(items)
+
+	assert.Equal(t, []models.ItemScore{{"item": "1"}, {"item": "3"}}, filtered)
+	assert.Equal(t, true, b.Contains("2"))
+	assert.Equal(t, false, b.Contains("3"))
+	assert.Equal(t, false, b.IsEmpty())
+
+	// the items in input are not modified
+	assert.Equal(t, "2", items[1]["item"])
+
+	// nothing to filter
+	assert.Equal(t, []models.ItemScore{{"item": "1"}}, b.Filter([]models.ItemScore{{"item": "1"}}))
+}
+
+func TestWatch(t *testing.T) {
+	dbc, err := db.NewRedisClient(testDBHost, db.Password(testDBPassword))
+	if err != nil {
+		t.FailNow()
+	}
+	defer dbc.Close()
+
+	b, err := NewBlocklist(dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	stop, err := b.Watch(time.Minute)
+	if err != nil {
+		t.FailNow()
+	}
+	defer stop()
+
+	if err := models.BlockItems([]string{"watched"}, dbc); err != nil {
+		t.FailNow()
+	}
+	defer models.UnblockItems([]string{"watched"}, dbc)
+
+	// the change is propagated through the subscription
+	for i := 0; i < 100 && !b.Contains("watched"); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, true, b.Contains("watched"))
+}
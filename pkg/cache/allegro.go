@@ -13,6 +13,7 @@ import (
 // AllegroBigCache is the struct holding the Cache layer object
 type AllegroBigCache struct {
 	*bigcache.BigCache
+	index *keyIndex
 }
 
 // Shards functional option
@@ -66,12 +67,18 @@ func NewAllegroBigCache(opts ...func(*bigcache.Config)) (*AllegroBigCache, error
 		opt(c)
 	}
 
-	cache, err := bigcache.NewBigCache(*c)
+	// the deleted entries are removed again once they expire, hence only expiry and lack of space are tracked
+	index := newKeyIndex()
+	c.OnRemove = nil
+	c.OnRemoveWithReason = func(key string, entry []byte, reason bigcache.RemoveReason) {
+		index.remove(key)
+	}
+	cache, err := bigcache.NewBigCache(c.OnRemoveFilterSet(bigcache.Expired, bigcache.NoSpace))
 	if err != nil {
 		log.Error().Str("CACHE", "failed to create client").Str("MSG", err.Error())
 		return nil, err
 	}
-	return &AllegroBigCache{cache}, nil
+	return &AllegroBigCache{cache, index}, nil
 }
 
 // Set stores a key/value pair with specified weight into the cache layer
@@ -81,18 +88,23 @@ func (ac *AllegroBigCache) Set(key string, value []models.ItemScore) bool {
 		log.Error().Str("CACHE", fmt.Sprintf("set key %s failed", key)).Str("MSG", err.Error())
 		return false
 	}
-	if err := ac.BigCache.Set(key, []byte(v)); err != nil {
+	return ac.SetBytes(key, v)
+}
+
+// SetBytes stores the serialized value into the cache layer
+func (ac *AllegroBigCache) SetBytes(key string, value []byte) bool {
+	if err := ac.BigCache.Set(key, value); err != nil {
 		log.Error().Str("CACHE", fmt.Sprintf("set key %s failed", key)).Str("MSG", err.Error())
 		return false
 	}
+	ac.index.add(key)
 	return true
 }
 
 // Get returns the value associated with the particular key
 func (ac *AllegroBigCache) Get(key string) ([]models.ItemScore, bool) {
-	v, err := ac.BigCache.Get(key)
-	if err != nil {
-		log.Error().Str("CACHE", fmt.Sprintf("get key %s failed", key)).Str("MSG", err.Error())
+	v, ok := ac.GetBytes(key)
+	if !ok {
 		return nil, false
 	}
 
@@ -104,6 +116,16 @@ func (ac *AllegroBigCache) Get(key string) ([]models.ItemScore, bool) {
 	return value, true
 }
 
+// GetBytes returns the serialized value associated with the particular key. The value is a copy owned by the caller
+func (ac *AllegroBigCache) GetBytes(key string) ([]byte, bool) {
+	v, err := ac.BigCache.Get(key)
+	if err != nil {
+		log.Error().Str("CACHE", fmt.Sprintf("get key %s failed", key)).Str("MSG", err.Error())
+		return nil, false
+	}
+	return v, true
+}
+
 // Del deletes the entry from the cache layer
 func (ac *AllegroBigCache) Del(key string) bool {
 	if err := ac.BigCache.Delete(key); err != nil {
@@ -113,15 +135,34 @@ func (ac *AllegroBigCache) Del(key string) bool {
 	return true
 }
 
+// EvictModel deletes all the entries of the model. The keys are taken from the index since the iterator of
+// bigcache does not return the keys reliably. Keys that already expired or have been deleted are not counted
+func (ac *AllegroBigCache) EvictModel(modelName string) int {
+	evicted := 0
+	for _, k := range ac.index.list(modelName) {
+		if err := ac.BigCache.Delete(k); err == nil {
+			evicted++
+		}
+	}
+	return evicted
+}
+
 // Empty clears the cache
 func (ac *AllegroBigCache) Empty() bool {
 	if err := ac.BigCache.Reset(); err != nil {
 		log.Error().Str("CACHE", "empty failed").Str("MSG", err.Error())
 		return false
 	}
+	ac.index.reset()
 	return true
 }
 
+// Stats returns the usage of the cache. The memory is the capacity allocated by the shards
+func (ac *AllegroBigCache) Stats() Stats {
+	s := ac.BigCache.Stats()
+	return NewStats(s.Hits, s.Misses, ac.BigCache.Len(), ac.BigCache.Capacity())
+}
+
 // Close closes the cache once it is not necessary anymore
 func (ac *AllegroBigCache) Close() {
 	if err := ac.BigCache.Close(); err != nil {
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"strconv"
@@ -187,6 +188,81 @@ func TestDel(t *testing.T) {
 	assert.Equal(t, 0, int(c.BigCache.Len()))
 }
 
+func TestEvictModel(t *testing.T) {
+	c := createAllegroBigCache()
+	defer c.Empty()
+	defer c.Close()
+
+	is := []models.ItemScore{
+		{
+			"score": "0.5",
+			"type":  "movie",
+			"item":  "42",
+		},
+	}
+	for _, k := range []string{Key("a", "1"), Key("a", "2"), Key("ab", "1")} {
+		if ok := c.Set(k, is); !ok {
+			t.Fail()
+		}
+	}
+
+	assert.Equal(t, 2, c.EvictModel("a"))
+	assert.Equal(t, 1, c.BigCache.Len())
+
+	_, ok := c.Get(Key("ab", "1"))
+	assert.True(t, ok)
+}
+
+func TestEvictModelExpired(t *testing.T) {
+	c, err := NewAllegroBigCache(Shards(16), LifeWindow(time.Second), CleanWindow(time.Second), MaxEntriesInWindow(100), MaxEntrySize(500))
+	if err != nil {
+		t.FailNow()
+	}
+	defer c.Close()
+
+	is := []models.ItemScore{{"item": "42"}}
+	for _, k := range []string{Key("a", "1"), Key("a", "1"), Key("a", "2")} {
+		if ok := c.Set(k, is); !ok {
+			t.Fail()
+		}
+	}
+	c.Del(Key("a", "2"))
+	assert.ElementsMatch(t, []string{Key("a", "1"), Key("a", "2")}, c.index.list("a"))
+
+	// the keys leave the index once bigcache removes their entries
+	time.Sleep(3 * time.Second)
+	assert.Empty(t, c.index.list("a"))
+	assert.Equal(t, 0, c.EvictModel("a"))
+}
+
+func TestStats(t *testing.T) {
+	c := createAllegroBigCache()
+	defer c.Empty()
+	defer c.Close()
+
+	is := []models.ItemScore{
+		{
+			"score": "0.5",
+			"type":  "movie",
+			"item":  "42",
+		},
+	}
+	if ok := c.Set("hello", is); !ok {
+		t.Fail()
+	}
+	c.Get("hello")
+	c.Get("hello")
+	c.Get("hello")
+	c.Get("world")
+
+	s := c.Stats()
+	assert.Equal(t, int64(3), s.Hits)
+	assert.Equal(t, int64(1), s.Misses)
+	assert.Equal(t, 0.75, s.HitRate)
+	assert.Equal(t, 1, s.Entries)
+	assert.True(t, s.Bytes > 0)
+}
+
 func BenchmarkWriteToCache(b *testing.B) {
 	for _, shards := range []int{1, 256, 512, 1024, 2048, 4096, 8192} {
 		b.Run(fmt.Sprintf("%d-shards", shards), func(b *testing.B) {
@@ -195,6 +271,34 @@ func BenchmarkWriteToCache(b *testing.B) {
 	}
 }
 
+// BenchmarkReadFromCache compares reading the deserialized recommendations with reading them already serialized,
+// as written to the response
+func BenchmarkReadFromCache(b *testing.B) {
+	ac := createAllegroBigCache()
+	defer ac.Close()
+
+	ac.Set("key", itemsBench)
+
+	b.Run("items", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			is, _ := ac.Get("key")
+			if _, err := json.Marshal(is); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("bytes", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			v, _ := ac.GetBytes("key")
+			if _, err := json.Marshal(json.RawMessage(v)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 func writeToCache(b *testing.B, shards int, lifeWindow time.Duration, requestsInLifeWindow int) {
 	ac, _ := NewAllegroBigCache(Shards(shards), LifeWindow(lifeWindow), MaxEntriesInWindow(max(requestsInLifeWindow, 100)), MaxEntrySize(500))
 	rand.Seed(time.Now().Unix())
@@ -1,6 +1,10 @@
 package cache
 
-import "github.com/rtlnl/phoenix/models"
+import (
+	"fmt"
+
+	"github.com/rtlnl/phoenix/models"
+)
 
 /*
 
@@ -24,6 +28,11 @@ BenchmarkWriteToCache/1024-shards-12      	  320841	      3379 ns/op	   10285 B/
 BenchmarkWriteToCache/2048-shards-12      	  342153	      3451 ns/op	   10319 B/op	     185 allocs/op
 BenchmarkWriteToCache/4096-shards-12      	  327483	      3529 ns/op	   10309 B/op	     185 allocs/op
 BenchmarkWriteToCache/8192-shards-12      	  340054	      3581 ns/op	   10497 B/op	     185 allocs/op
+
+Reading the value already serialized avoids deserializing it only for serializing it again in the response
+
+BenchmarkReadFromCache/items              	   22118	     49394 ns/op	    8448 B/op	     145 allocs/op
+BenchmarkReadFromCache/bytes              	  471871	      2906 ns/op	    1456 B/op	       4 allocs/op
 */
 
 // Cache is the interface that will be used to create a caching layer to speedup the
@@ -31,6 +40,18 @@ BenchmarkWriteToCache/8192-shards-12      	  340054	      3581 ns/op	   10497 B/
 type Cache interface {
 	Set(key string, value []models.ItemScore) bool
 	Get(key string) ([]models.ItemScore, bool)
+	// SetBytes and GetBytes store and return the value already serialized in JSON, so that it can be written
+	// to the response without deserializing it
+	SetBytes(key string, value []byte) bool
+	GetBytes(key string) ([]byte, bool)
 	Del(key string) bool
+	// EvictModel deletes all the entries of the model and returns how many have been deleted
+	EvictModel(modelName string) int
 	Empty() bool
+	Stats() Stats
+}
+
+// Key returns the key under which the recommendations of the signal of the model are cached
+func Key(modelName, signalID string) string {
+	return fmt.Sprintf("%s#%s", modelName, signalID)
 }
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+)
+
+// errLoadAborted is returned to the callers waiting for a load that did not return, i.e. because it panicked
+var errLoadAborted = errors.New("load aborted before returning")
+
+// Flight coalesces the concurrent loads of the same key after a cache miss. Only the first caller runs the load
+// while the others wait for its result, so that a popular key missing from the cache reaches the database once
+type Flight struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg    sync.WaitGroup
+	value interface{}
+	err   error
+}
+
+// Do runs the load of the key unless a load of the same key is already running, in which case it waits for its
+// result. It returns whether the result has been shared with other callers
+func (f *Flight) Do(key string, load func() (interface{}, error)) (interface{}, error, bool) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]*flightCall)
+	}
+	if c, ok := f.calls[key]; ok {
+		f.mu.Unlock()
+		c.wg.Wait()
+		return c.value, c.err, true
+	}
+	c := &flightCall{}
+	c.wg.Add(1)
+	f.calls[key] = c
+	f.mu.Unlock()
+
+	// the key is released even if the load panics, so that the waiting callers are not blocked forever. They
+	// receive an error instead of an empty value
+	returned := false
+	defer func() {
+		if !returned {
+			c.value, c.err = nil, errLoadAborted
+		}
+		f.mu.Lock()
+		delete(f.calls, key)
+		f.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	c.value, c.err = load()
+	returned = true
+	return c.value, c.err, false
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlightDo(t *testing.T) {
+	var f Flight
+
+	v, err, shared := f.Do("key", func() (interface{}, error) {
+		return "value", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "value", v)
+	assert.False(t, shared)
+
+	_, err, _ = f.Do("key", func() (interface{}, error) {
+		return nil, errors.New("load failed")
+	})
+	if assert.Error(t, err) {
+		assert.Equal(t, "load failed", err.Error())
+	}
+}
+
+func TestFlightDoCoalesce(t *testing.T) {
+	var f Flight
+	var loads int32
+	release := make(chan struct{})
+
+	load := func() (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, _ := f.Do("key", load)
+			assert.NoError(t, err)
+			assert.Equal(t, "value", v)
+		}()
+	}
+
+	// wait for all the callers to reach the running load
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	// the key is loaded again once the previous load is done
+	f.Do("key", func() (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		return "value", nil
+	})
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loads))
+}
+
+func TestFlightDoPanic(t *testing.T) {
+	var f Flight
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go func() {
+		defer func() { recover() }()
+		f.Do("key", func() (interface{}, error) {
+			close(started)
+			<-release
+			panic("load failed")
+		})
+	}()
+
+	<-started
+	done := make(chan error)
+	go func() {
+		_, err, shared := f.Do("key", func() (interface{}, error) {
+			return "value", nil
+		})
+		assert.True(t, shared)
+		done <- err
+	}()
+
+	// wait for the caller to reach the running load
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	assert.Equal(t, errLoadAborted, <-done)
+}
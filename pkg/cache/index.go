@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+)
+
+// keyIndex keeps track of the keys stored for every model so that the entries of a model can be evicted
+// without scanning the whole cache. Every key counts the entries of bigcache holding it: an entry that is
+// overwritten or deleted is still kept by bigcache until it is removed for expiry or for lack of space, hence
+// a key leaves the index only once all its entries have been removed
+type keyIndex struct {
+	mu   sync.Mutex
+	keys map[string]map[string]int
+}
+
+func newKeyIndex() *keyIndex {
+	return &keyIndex{keys: make(map[string]map[string]int)}
+}
+
+// modelOf returns the model name of a key built with Key
+func modelOf(key string) string {
+	if i := strings.Index(key, "#"); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// add counts a new entry of the key
+func (ki *keyIndex) add(key string) {
+	ki.count(key, 1)
+}
+
+// remove counts an entry of the key removed by the cache
+func (ki *keyIndex) remove(key string) {
+	ki.count(key, -1)
+}
+
+// count changes the number of entries of the key. An entry might be removed before being added to the index
+// when the cache evicts it right after the set, hence the counter can be temporarily negative
+func (ki *keyIndex) count(key string, delta int) {
+	ki.mu.Lock()
+	defer ki.mu.Unlock()
+
+	m := modelOf(key)
+	if ki.keys[m] == nil {
+		ki.keys[m] = make(map[string]int)
+	}
+	ki.keys[m][key] += delta
+	if ki.keys[m][key] == 0 {
+		delete(ki.keys[m], key)
+	}
+	if len(ki.keys[m]) == 0 {
+		delete(ki.keys, m)
+	}
+}
+
+// list returns the keys of the model
+func (ki *keyIndex) list(modelName string) []string {
+	ki.mu.Lock()
+	defer ki.mu.Unlock()
+
+	keys := make([]string, 0, len(ki.keys[modelName]))
+	for k, n := range ki.keys[modelName] {
+		if n > 0 {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (ki *keyIndex) reset() {
+	ki.mu.Lock()
+	defer ki.mu.Unlock()
+
+	ki.keys = make(map[string]map[string]int)
+}
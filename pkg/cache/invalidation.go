@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/db"
+)
+
+// Invalidate evicts the entries of the cache that are stale after the change in input
+func Invalidate(c Cache, ci models.CacheInvalidation) {
+	if ci.SignalID == "" {
+		c.EvictModel(ci.ModelName)
+		return
+	}
+	// a missing key is not an error since the signal might not be cached
+	c.Del(Key(ci.ModelName, ci.SignalID))
+}
+
+// WatchInvalidations evicts the stale entries of the cache every time a change of the recommendations is
+// published on models.CacheChannel. Since published messages are lost while the subscription is reconnecting,
+// the entries changed in the meantime are served until they expire. It returns the function for stopping the watcher
+func WatchInvalidations(c Cache, dbc db.DB) (func() error, error) {
+	msgs, unsubscribe, err := dbc.Subscribe(models.CacheChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for msg := range msgs {
+			ci, err := models.DeserializeCacheInvalidation(msg)
+			if err != nil {
+				log.Error().Msgf("could not deserialize cache invalidation %s. error: %s", msg, err.Error())
+				continue
+			}
+			Invalidate(c, ci)
+		}
+	}()
+	return unsubscribe, nil
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/db"
+	"github.com/rtlnl/phoenix/utils"
+)
+
+var (
+	testDBHost     = utils.GetEnv("DB_HOST", "127.0.0.1:6379")
+	testDBPassword = utils.GetEnv("DB_PASSWORD", "")
+)
+
+func TestInvalidate(t *testing.T) {
+	ac := createAllegroBigCache()
+	defer ac.Close()
+
+	ac.Set(Key("model", "1"), []models.ItemScore{{"item": "a"}})
+	ac.Set(Key("model", "2"), []models.ItemScore{{"item": "b"}})
+
+	Invalidate(ac, models.CacheInvalidation{ModelName: "model", SignalID: "1"})
+
+	_, ok := ac.Get(Key("model", "1"))
+	assert.False(t, ok)
+	_, ok = ac.Get(Key("model", "2"))
+	assert.True(t, ok)
+
+	// the whole model is stale
+	Invalidate(ac, models.CacheInvalidation{ModelName: "model"})
+
+	_, ok = ac.Get(Key("model", "2"))
+	assert.False(t, ok)
+}
+
+func TestWatchInvalidations(t *testing.T) {
+	dbc, err := db.NewRedisClient(testDBHost, db.Password(testDBPassword))
+	if err != nil {
+		t.FailNow()
+	}
+	defer dbc.Close()
+
+	ac := createAllegroBigCache()
+	defer ac.Close()
+
+	stop, err := WatchInvalidations(ac, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+	defer stop()
+
+	ac.Set(Key("watched", "1"), []models.ItemScore{{"item": "a"}})
+
+	if err := models.InvalidateSignal("watched", "1", dbc); err != nil {
+		t.FailNow()
+	}
+
+	// the change is propagated through the subscription
+	ok := true
+	for i := 0; i < 100 && ok; i++ {
+		time.Sleep(10 * time.Millisecond)
+		_, ok = ac.Get(Key("watched", "1"))
+	}
+	assert.False(t, ok)
+}
@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/rtlnl/phoenix/models"
+)
+
+// LRU is an in-process cache bounded by the number of entries. When full, the least recently used entry is
+// evicted. Every entry expires after its own time to live
+type LRU struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List
+	maxEntries int
+	ttl        time.Duration
+	bytes      int
+	hits       int64
+	misses     int64
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// TTL functional option. The default time to live of the entries
+func TTL(d time.Duration) func(*LRU) {
+	return func(l *LRU) {
+		l.ttl = d
+	}
+}
+
+// NewLRU returns a new LRU cache holding up to maxEntries entries
+func NewLRU(maxEntries int, opts ...func(*LRU)) (*LRU, error) {
+	if maxEntries <= 0 {
+		return nil, fmt.Errorf("maximum number of entries must be positive")
+	}
+
+	l := &LRU{
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		maxEntries: maxEntries,
+		ttl:        10 * time.Minute,
+	}
+
+	// call option functions on instance to set options on it
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l, nil
+}
+
+// Set stores a key/value pair into the cache with the default time to live
+func (l *LRU) Set(key string, value []models.ItemScore) bool {
+	return l.SetWithTTL(key, value, l.ttl)
+}
+
+// SetWithTTL stores a key/value pair into the cache that expires after the time to live in input
+func (l *LRU) SetWithTTL(key string, value []models.ItemScore, ttl time.Duration) bool {
+	v, err := json.Marshal(value)
+	if err != nil {
+		log.Error().Str("CACHE", fmt.Sprintf("set key %s failed", key)).Str("MSG", err.Error())
+		return false
+	}
+	return l.SetBytesWithTTL(key, v, ttl)
+}
+
+// SetBytes stores the serialized value into the cache with the default time to live
+func (l *LRU) SetBytes(key string, value []byte) bool {
+	return l.SetBytesWithTTL(key, value, l.ttl)
+}
+
+// SetBytesWithTTL stores the serialized value into the cache that expires after the time to live in input.
+// The value must not be modified afterwards
+func (l *LRU) SetBytesWithTTL(key string, value []byte, ttl time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.entries[key]; ok {
+		l.remove(el)
+	}
+	l.entries[key] = l.order.PushFront(&lruEntry{
+		key:       key,
+		value:     value,
+		expiresAt: time.Now().Add(ttl),
+	})
+	l.bytes += len(value)
+
+	for l.order.Len() > l.maxEntries {
+		l.remove(l.order.Back())
+	}
+	return true
+}
+
+// Get returns the value associated with the particular key. Expired entries are deleted
+func (l *LRU) Get(key string) ([]models.ItemScore, bool) {
+	v, ok := l.GetBytes(key)
+	if !ok {
+		return nil, false
+	}
+
+	var value []models.ItemScore
+	if err := json.Unmarshal(v, &value); err != nil {
+		log.Error().Str("CACHE", fmt.Sprintf("get key %s failed", key)).Str("MSG", err.Error())
+		return nil, false
+	}
+	return value, true
+}
+
+// GetBytes returns the serialized value associated with the particular key. The value is shared with the cache
+// and must not be modified. Expired entries are deleted
+func (l *LRU) GetBytes(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.entries[key]
+	if ok && time.Now().After(el.Value.(*lruEntry).expiresAt) {
+		l.remove(el)
+		ok = false
+	}
+	if !ok {
+		l.misses++
+		return nil, false
+	}
+	l.hits++
+	l.order.MoveToFront(el)
+	return el.Value.(*lruEntry).value, true
+}
+
+// Del deletes the entry from the cache
+func (l *LRU) Del(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.entries[key]
+	if !ok {
+		return false
+	}
+	l.remove(el)
+	return true
+}
+
+// EvictModel deletes all the entries of the model
+func (l *LRU) EvictModel(modelName string) int {
+	prefix := Key(modelName, "")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	evicted := 0
+	for k, el := range l.entries {
+		if strings.HasPrefix(k, prefix) {
+			l.remove(el)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// Empty clears the cache
+func (l *LRU) Empty() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = make(map[string]*list.Element)
+	l.order.Init()
+	l.bytes = 0
+	return true
+}
+
+// Stats returns the usage of the cache. The memory is the size of the stored values
+func (l *LRU) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return NewStats(l.hits, l.misses, len(l.entries), l.bytes)
+}
+
+// remove deletes the element from the cache. The lock must be held by the caller
+func (l *LRU) remove(el *list.Element) {
+	e := l.order.Remove(el).(*lruEntry)
+	delete(l.entries, e.key)
+	l.bytes -= len(e.value)
+}
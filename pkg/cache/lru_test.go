@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rtlnl/phoenix/models"
+)
+
+func TestNewLRU(t *testing.T) {
+	l, err := NewLRU(10)
+	if err != nil {
+		t.Fail()
+	}
+	assert.NotNil(t, l)
+}
+
+func TestNewLRUFail(t *testing.T) {
+	_, err := NewLRU(0)
+	if assert.Error(t, err) {
+		assert.Equal(t, "maximum number of entries must be positive", err.Error())
+	}
+}
+
+func TestLRUSetGet(t *testing.T) {
+	l, _ := NewLRU(10)
+
+	is := []models.ItemScore{{"item": "42", "score": "0.5"}}
+	assert.True(t, l.Set("hello", is))
+
+	v, ok := l.Get("hello")
+	assert.True(t, ok)
+	assert.Equal(t, is, v)
+
+	_, ok = l.Get("world")
+	assert.False(t, ok)
+}
+
+func TestLRUEvictLeastRecentlyUsed(t *testing.T) {
+	l, _ := NewLRU(2)
+
+	l.Set("a", []models.ItemScore{{"item": "a"}})
+	l.Set("b", []models.ItemScore{{"item": "b"}})
+	// a becomes the most recently used
+	l.Get("a")
+	l.Set("c", []models.ItemScore{{"item": "c"}})
+
+	_, ok := l.Get("b")
+	assert.False(t, ok)
+	_, ok = l.Get("a")
+	assert.True(t, ok)
+	_, ok = l.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 2, l.Stats().Entries)
+}
+
+func TestLRUExpiry(t *testing.T) {
+	l, _ := NewLRU(10, TTL(time.Hour))
+
+	l.SetWithTTL("short", []models.ItemScore{{"item": "a"}}, time.Millisecond)
+	l.Set("long", []models.ItemScore{{"item": "b"}})
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok := l.Get("short")
+	assert.False(t, ok)
+	_, ok = l.Get("long")
+	assert.True(t, ok)
+	assert.Equal(t, 1, l.Stats().Entries)
+}
+
+func TestLRUDelEvictModelEmpty(t *testing.T) {
+	l, _ := NewLRU(10)
+
+	l.Set(Key("a", "1"), []models.ItemScore{{"item": "1"}})
+	l.Set(Key("a", "2"), []models.ItemScore{{"item": "2"}})
+	l.Set(Key("ab", "1"), []models.ItemScore{{"item": "1"}})
+	l.Set(Key("b", "1"), []models.ItemScore{{"item": "1"}})
+
+	assert.True(t, l.Del(Key("b", "1")))
+	assert.False(t, l.Del(Key("b", "1")))
+	assert.Equal(t, 2, l.EvictModel("a"))
+	assert.Equal(t, 1, l.Stats().Entries)
+
+	assert.True(t, l.Empty())
+	s := l.Stats()
+	assert.Equal(t, 0, s.Entries)
+	assert.Equal(t, 0, s.Bytes)
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/rtlnl/phoenix/pkg/metrics"
+)
+
+// Stats contains the usage of the cache since it has been created
+type Stats struct {
+	Hits    int64   `json:"hits" description:"number of keys found in the cache"`
+	Misses  int64   `json:"misses" description:"number of keys not found in the cache"`
+	HitRate float64 `json:"hitRate" description:"ratio of the keys found in the cache over all the keys requested"`
+	Entries int     `json:"entries" description:"number of entries currently in the cache"`
+	Bytes   int     `json:"bytes" description:"memory used for storing the entries"`
+}
+
+// NewStats returns the statistics of the cache computing the hit rate
+func NewStats(hits, misses int64, entries, bytes int) Stats {
+	s := Stats{
+		Hits:    hits,
+		Misses:  misses,
+		Entries: entries,
+		Bytes:   bytes,
+	}
+	if hits+misses > 0 {
+		s.HitRate = float64(hits) / float64(hits+misses)
+	}
+	return s
+}
+
+// ExportStats sends the usage of the cache to the metrics every interval. It returns the function for stopping
+// the export
+func ExportStats(c Cache, mc metrics.Metrics, interval time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s := c.Stats()
+				mc.CacheStats(s.Hits, s.Misses, s.Entries, s.Bytes)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
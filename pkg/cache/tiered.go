@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/db"
+)
+
+// Tiered is a cache composed by a local LRU in front of a cache shared by all the public instances and stored
+// in the database. The shared cache holds the serialized recommendations together with their expiry, so that
+// a miss of the local cache does not need to compute the recommendations again. The expired entries are deleted
+// by the sweeper of the worker together with the expired signals
+type Tiered struct {
+	local     *LRU
+	shared    db.DB
+	sharedTTL time.Duration
+	hits      int64
+	misses    int64
+}
+
+// sharedEntry is the format of the entries of the shared cache. The recommendations are kept serialized
+type sharedEntry struct {
+	ExpiresAt   time.Time       `json:"expiresAt"`
+	Recommended json.RawMessage `json:"recommended"`
+}
+
+// NewTiered returns a new Tiered cache. The entries of the shared cache expire after sharedTTL
+func NewTiered(local *LRU, shared db.DB, sharedTTL time.Duration) *Tiered {
+	return &Tiered{
+		local:     local,
+		shared:    shared,
+		sharedTTL: sharedTTL,
+	}
+}
+
+// Set stores a key/value pair in both the local and the shared cache
+func (t *Tiered) Set(key string, value []models.ItemScore) bool {
+	v, err := json.Marshal(value)
+	if err != nil {
+		log.Error().Str("CACHE", fmt.Sprintf("set key %s failed", key)).Str("MSG", err.Error())
+		return false
+	}
+	return t.SetBytes(key, v)
+}
+
+// SetBytes stores the serialized value in both the local and the shared cache
+func (t *Tiered) SetBytes(key string, value []byte) bool {
+	ok := t.local.SetBytes(key, value)
+
+	expiresAt := time.Now().Add(t.sharedTTL)
+	v, err := json.Marshal(sharedEntry{ExpiresAt: expiresAt, Recommended: value})
+	if err != nil {
+		log.Error().Str("CACHE", fmt.Sprintf("set key %s failed", key)).Str("MSG", err.Error())
+		return false
+	}
+	table, field := sharedKey(key)
+	if err := t.shared.AddOne(table, field, string(v)); err != nil {
+		log.Error().Str("CACHE", fmt.Sprintf("set key %s failed", key)).Str("MSG", err.Error())
+		return false
+	}
+	if err := t.shared.SetExpiry(table, field, expiresAt); err != nil {
+		log.Error().Str("CACHE", fmt.Sprintf("set key %s failed", key)).Str("MSG", err.Error())
+		return false
+	}
+	return ok
+}
+
+// Get returns the value associated with the particular key looking first in the local cache and then in
+// the shared one
+func (t *Tiered) Get(key string) ([]models.ItemScore, bool) {
+	v, ok := t.GetBytes(key)
+	if !ok {
+		return nil, false
+	}
+
+	var value []models.ItemScore
+	if err := json.Unmarshal(v, &value); err != nil {
+		log.Error().Str("CACHE", fmt.Sprintf("get key %s failed", key)).Str("MSG", err.Error())
+		return nil, false
+	}
+	return value, true
+}
+
+// GetBytes returns the serialized value associated with the particular key looking first in the local cache
+// and then in the shared one. A value found in the shared cache is stored in the local one until it expires
+func (t *Tiered) GetBytes(key string) ([]byte, bool) {
+	if v, ok := t.local.GetBytes(key); ok {
+		atomic.AddInt64(&t.hits, 1)
+		return v, true
+	}
+
+	table, field := sharedKey(key)
+	s, err := t.shared.GetOne(table, field)
+	if err != nil {
+		// the key is not in the shared cache
+		atomic.AddInt64(&t.misses, 1)
+		return nil, false
+	}
+	var e sharedEntry
+	if err := json.Unmarshal([]byte(s), &e); err != nil {
+		log.Error().Str("CACHE", fmt.Sprintf("get key %s failed", key)).Str("MSG", err.Error())
+		atomic.AddInt64(&t.misses, 1)
+		return nil, false
+	}
+	ttl := time.Until(e.ExpiresAt)
+	if ttl <= 0 {
+		atomic.AddInt64(&t.misses, 1)
+		return nil, false
+	}
+
+	if ttl > t.local.ttl {
+		ttl = t.local.ttl
+	}
+	t.local.SetBytesWithTTL(key, e.Recommended, ttl)
+
+	atomic.AddInt64(&t.hits, 1)
+	return e.Recommended, true
+}
+
+// Del deletes the entry from both the local and the shared cache
+func (t *Tiered) Del(key string) bool {
+	ok := t.local.Del(key)
+
+	table, field := sharedKey(key)
+	if err := t.shared.DeleteOne(table, field); err != nil {
+		return ok
+	}
+	if err := t.shared.RemoveExpiry(table, field); err != nil {
+		log.Error().Str("CACHE", fmt.Sprintf("delete key %s failed", key)).Str("MSG", err.Error())
+	}
+	return true
+}
+
+// EvictModel deletes all the entries of the model from both the local and the shared cache. It returns how
+// many entries have been deleted from the local cache
+func (t *Tiered) EvictModel(modelName string) int {
+	evicted := t.local.EvictModel(modelName)
+	if err := t.shared.DropTable(models.CacheTable(modelName)); err != nil {
+		log.Error().Str("CACHE", fmt.Sprintf("evict model %s failed", modelName)).Str("MSG", err.Error())
+	}
+	return evicted
+}
+
+// Empty clears the local cache. The shared cache is used by the other instances as well, hence its entries
+// are only evicted per model or when they expire
+func (t *Tiered) Empty() bool {
+	return t.local.Empty()
+}
+
+// Stats returns the usage of the cache. Hits and misses count both levels while the entries and the memory
+// are the ones of the local cache
+func (t *Tiered) Stats() Stats {
+	s := t.local.Stats()
+	return NewStats(atomic.LoadInt64(&t.hits), atomic.LoadInt64(&t.misses), s.Entries, s.Bytes)
+}
+
+// sharedKey returns the table and the key under which the entry is stored in the shared cache
+func sharedKey(key string) (string, string) {
+	modelName := modelOf(key)
+	return models.CacheTable(modelName), strings.TrimPrefix(key, Key(modelName, ""))
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/db"
+)
+
+func createTiered(t *testing.T) (*Tiered, db.DB) {
+	dbc, err := db.NewRedisClient(testDBHost, db.Password(testDBPassword))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLRU(10, TTL(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTiered(l, dbc, time.Hour), dbc
+}
+
+func TestTieredSetGet(t *testing.T) {
+	tc, dbc := createTiered(t)
+	defer dbc.Close()
+	defer dbc.DropTable("tiered@cache")
+
+	is := []models.ItemScore{{"item": "42", "score": "0.5"}}
+	assert.True(t, tc.Set(Key("tiered", "1"), is))
+
+	// the entry is served by the shared cache once the local one is empty
+	tc.Empty()
+	v, ok := tc.Get(Key("tiered", "1"))
+	assert.True(t, ok)
+	assert.Equal(t, is, v)
+
+	// the local cache has been filled again
+	assert.Equal(t, 1, tc.Stats().Entries)
+
+	_, ok = tc.Get(Key("tiered", "2"))
+	assert.False(t, ok)
+
+	s := tc.Stats()
+	assert.Equal(t, int64(1), s.Hits)
+	assert.Equal(t, int64(1), s.Misses)
+}
+
+func TestTieredSharedExpired(t *testing.T) {
+	tc, dbc := createTiered(t)
+	defer dbc.Close()
+	defer dbc.DropTable("tiered@cache")
+
+	v := `{"expiresAt":"2020-01-01T00:00:00Z","recommended":[{"item":"42"}]}`
+	if err := dbc.AddOne("tiered@cache", "1", v); err != nil {
+		t.Fatal(err)
+	}
+
+	_, ok := tc.Get(Key("tiered", "1"))
+	assert.False(t, ok)
+
+	// the entries are deleted from the shared cache once they expire
+	tc.Set(Key("tiered", "2"), []models.ItemScore{{"item": "2"}})
+	keys, err := dbc.DeleteExpired("tiered@cache", time.Now().Add(2*time.Hour), 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2"}, keys)
+}
+
+func TestTieredDelEvictModel(t *testing.T) {
+	tc, dbc := createTiered(t)
+	defer dbc.Close()
+	defer dbc.DropTable("other@cache")
+
+	tc.Set(Key("tiered", "1"), []models.ItemScore{{"item": "1"}})
+	tc.Set(Key("tiered", "2"), []models.ItemScore{{"item": "2"}})
+	tc.Set(Key("other", "1"), []models.ItemScore{{"item": "1"}})
+
+	assert.True(t, tc.Del(Key("tiered", "1")))
+	_, err := dbc.GetOne("tiered@cache", "1")
+	assert.Error(t, err)
+
+	assert.Equal(t, 1, tc.EvictModel("tiered"))
+	_, err = dbc.GetOne("tiered@cache", "2")
+	assert.Error(t, err)
+
+	_, ok := tc.Get(Key("other", "1"))
+	assert.True(t, ok)
+}
@@ -1,15 +1,53 @@
 package db
 
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is matched with errors.Is by the errors returned when a key does not exist, so that it can be
+// told apart from the failures of the database
+var ErrNotFound = errors.New("not found")
+
+// notFoundError is the error of a missing key. It keeps the key in the message
+type notFoundError struct {
+	key string
+}
+
+func (e notFoundError) Error() string {
+	return fmt.Sprintf("key %s not found", e.key)
+}
+
+// Is reports that the error is ErrNotFound
+func (e notFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
 // DB is the interface that will allow to use different backends
 // for storing data into the database
 type DB interface {
 	GetOne(table string, key string) (string, error)
+	GetMany(keys map[string][]string) (map[string]map[string]string, error)
 	AddOne(table string, key string, value string) error
+	AddOneWithExpiry(table, key, value string, expiresAt *time.Time) error
 	GetAllRecords(table string) (map[string]string, int, error)
+	IterateRecords(table string, fn func(key, value string) error) error
 	DeleteOne(table string, key string) error
 	DropTable(table string) error
+	RenameTable(from, to string) error
 	PipelineAddOne(table, key string, values string)
 	PipelineExec() error
+	SetExpiry(table, key string, expiresAt time.Time) error
+	RemoveExpiry(table, key string) error
+	PipelineSetExpiry(table, key string, expiresAt time.Time)
+	DeleteExpired(table string, until time.Time, limit int) ([]string, error)
+	CompareAndSwap(table, key, checksum, value string, expiresAt *time.Time) (bool, error)
+	CompareAndDelete(table, key, checksum string) (bool, error)
+	Publish(channel, message string) error
+	Subscribe(channel string) (<-chan string, func() error, error)
 	Close() error
 	Health() error
 }
@@ -23,4 +61,14 @@ const (
 	// maximum number of elements return per scan in Redis. A large amount of Scan elements values, benefits the database
 	// query system
 	maxScan = 1000
+	// AnyChecksum matches any existing value in CompareAndSwap and CompareAndDelete
+	AnyChecksum = "*"
+	// NoChecksum matches only a missing key in CompareAndSwap
+	NoChecksum = ""
 )
+
+// Checksum returns the SHA1 of the value used for detecting concurrent changes in CompareAndSwap and CompareAndDelete
+func Checksum(value string) string {
+	h := sha1.Sum([]byte(value))
+	return hex.EncodeToString(h[:])
+}
@@ -18,8 +18,51 @@ const (
 	TTL = 30 * time.Second
 	// TTLRefreshInterval intervals for refreshing the TTL of the lock
 	TTLRefreshInterval = 10 * time.Second
+	// format of the sorted set indexing the keys of a table by expiry. Redis cannot expire single fields of a hash
+	expiryIndexFormat = "%s@expiry"
 )
 
+// deleteExpiredScript atomically deletes from the table the keys expired until ARGV[1] together with their
+// entries in the expiry index. At most ARGV[2] keys are deleted
+var deleteExpiredScript = redis.NewScript(`
+local keys = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, k in ipairs(keys) do
+	redis.call('HDEL', KEYS[1], k)
+	redis.call('ZREM', KEYS[2], k)
+end
+return keys
+`)
+
+// compareAndSwapScript atomically sets the key ARGV[1] of the table to ARGV[3] when the SHA1 of the current value
+// matches ARGV[2]. The expiry of the key is set to ARGV[4] or removed when empty
+var compareAndSwapScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], ARGV[1])
+if ARGV[2] == '' then
+	if current then return 0 end
+elseif not current or (ARGV[2] ~= '*' and redis.sha1hex(current) ~= ARGV[2]) then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+if ARGV[4] == '' then
+	redis.call('ZREM', KEYS[2], ARGV[1])
+else
+	redis.call('ZADD', KEYS[2], ARGV[4], ARGV[1])
+end
+return 1
+`)
+
+// compareAndDeleteScript atomically deletes the key ARGV[1] of the table together with its expiry when the SHA1
+// of the current value matches ARGV[2]
+var compareAndDeleteScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], ARGV[1])
+if not current or (ARGV[2] ~= '*' and redis.sha1hex(current) ~= ARGV[2]) then
+	return 0
+end
+redis.call('HDEL', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+return 1
+`)
+
 // Redis is a wrapper struct around Redis official package
 type Redis struct {
 	*redis.Client
@@ -67,13 +110,42 @@ func (db *Redis) Health() error {
 	return db.Ping().Err()
 }
 
-// GetOne returns the value associated with that key
+// GetOne returns the value associated with that key in a single round trip. A missing key returns an error
+// matching ErrNotFound
 func (db *Redis) GetOne(table, key string) (string, error) {
-	ok, err := db.Client.HExists(table, key).Result()
-	if !ok || err == redis.Nil || err != nil {
-		return "", fmt.Errorf("key %s not found", key)
+	v, err := db.Client.HGet(table, key).Result()
+	if err == redis.Nil {
+		return "", notFoundError{key}
 	}
-	return db.Client.HGet(table, key).Result()
+	return v, err
+}
+
+// GetMany returns the values associated with the keys of each table in a single round trip. The keys in input
+// are grouped by table. Only the keys found are returned
+func (db *Redis) GetMany(keys map[string][]string) (map[string]map[string]string, error) {
+	pipe := db.Client.Pipeline()
+	cmds := make(map[string]*redis.SliceCmd, len(keys))
+	for table, k := range keys {
+		if len(k) > 0 {
+			cmds[table] = pipe.HMGet(table, k...)
+		}
+	}
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	res := make(map[string]map[string]string, len(cmds))
+	for table, cmd := range cmds {
+		values := make(map[string]string)
+		for i, v := range cmd.Val() {
+			// missing keys are returned as nil
+			if s, ok := v.(string); ok {
+				values[keys[table][i]] = s
+			}
+		}
+		res[table] = values
+	}
+	return res, nil
 }
 
 // AddOne store the key/value in the redis
@@ -81,13 +153,33 @@ func (db *Redis) AddOne(table, key string, values string) error {
 	return db.Client.HSet(table, key, values).Err()
 }
 
-// DeleteOne deletes a key from a table
+// AddOneWithExpiry atomically sets the value of the key together with its expiry, so that the sweeper never
+// sees the new value with the old expiry. A nil expiry removes the expiry of the key
+func (db *Redis) AddOneWithExpiry(table, key, value string, expiresAt *time.Time) error {
+	_, err := db.Client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.HSet(table, key, value)
+		if expiresAt == nil {
+			pipe.ZRem(expiryIndex(table), key)
+		} else {
+			pipe.ZAdd(expiryIndex(table), &redis.Z{Score: float64(expiresAt.Unix()), Member: key})
+		}
+		return nil
+	})
+	return err
+}
+
+// DeleteOne deletes a key from a table together with its expiry
 func (db *Redis) DeleteOne(table, key string) error {
 	ok, err := db.Client.HExists(table, key).Result()
 	if !ok || err == redis.Nil || err != nil {
 		return fmt.Errorf("key %s not found", key)
 	}
-	return db.Client.HDel(table, key).Err()
+	_, err = db.Client.TxPipelined(func(p redis.Pipeliner) error {
+		p.HDel(table, key)
+		p.ZRem(expiryIndex(table), key)
+		return nil
+	})
+	return err
 }
 
 // DropTable deletes all the keys and the table itself
@@ -96,7 +188,107 @@ func (db *Redis) DropTable(table string) error {
 	if err == redis.Nil || err != nil {
 		return fmt.Errorf("key %s not found", table)
 	}
-	return db.Client.Del(table).Err()
+	return db.Client.Del(table, expiryIndex(table)).Err()
+}
+
+// RenameTable atomically renames the table, replacing the destination table if it exists. The expiries of the
+// keys follow the table
+func (db *Redis) RenameTable(from, to string) error {
+	n, err := db.Client.Exists(expiryIndex(from)).Result()
+	if err != nil {
+		return err
+	}
+	_, err = db.Client.TxPipelined(func(p redis.Pipeliner) error {
+		p.Rename(from, to)
+		if n > 0 {
+			p.Rename(expiryIndex(from), expiryIndex(to))
+		} else {
+			p.Del(expiryIndex(to))
+		}
+		return nil
+	})
+	return err
+}
+
+// SetExpiry sets the time after which the key of the table is deleted by DeleteExpired
+func (db *Redis) SetExpiry(table, key string, expiresAt time.Time) error {
+	return db.Client.ZAdd(expiryIndex(table), &redis.Z{Score: float64(expiresAt.Unix()), Member: key}).Err()
+}
+
+// RemoveExpiry removes the expiry of the key of the table
+func (db *Redis) RemoveExpiry(table, key string) error {
+	return db.Client.ZRem(expiryIndex(table), key).Err()
+}
+
+// PipelineSetExpiry queues the SetExpiry operation to the pipeline
+func (db *Redis) PipelineSetExpiry(table, key string, expiresAt time.Time) {
+	db.Pipeliner.ZAdd(expiryIndex(table), &redis.Z{Score: float64(expiresAt.Unix()), Member: key})
+}
+
+// DeleteExpired deletes at most limit keys of the table that expired until the time in input. It returns the
+// deleted keys
+func (db *Redis) DeleteExpired(table string, until time.Time, limit int) ([]string, error) {
+	res, err := deleteExpiredScript.Run(db.Client, []string{table, expiryIndex(table)}, until.Unix(), limit).Result()
+	if err != nil {
+		return nil, err
+	}
+	vals, ok := res.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected result %v when deleting the expired keys of %s", res, table)
+	}
+	keys := make([]string, 0, len(vals))
+	for _, v := range vals {
+		keys = append(keys, fmt.Sprint(v))
+	}
+	return keys, nil
+}
+
+// CompareAndSwap sets the value of the key only if the checksum of the current value matches the one in input.
+// AnyChecksum matches any existing value while NoChecksum matches only a missing key. A nil expiry removes the
+// expiry of the key. It returns false when the value changed in the meantime
+func (db *Redis) CompareAndSwap(table, key, checksum, value string, expiresAt *time.Time) (bool, error) {
+	expiry := ""
+	if expiresAt != nil {
+		expiry = fmt.Sprint(expiresAt.Unix())
+	}
+	n, err := compareAndSwapScript.Run(db.Client, []string{table, expiryIndex(table)}, key, checksum, value, expiry).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// CompareAndDelete deletes the key only if the checksum of the current value matches the one in input. AnyChecksum
+// matches any existing value. It returns false when the key is missing or the value changed in the meantime
+func (db *Redis) CompareAndDelete(table, key, checksum string) (bool, error) {
+	n, err := compareAndDeleteScript.Run(db.Client, []string{table, expiryIndex(table)}, key, checksum).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// expiryIndex returns the name of the sorted set indexing the keys of the table by expiry
+func expiryIndex(table string) string {
+	return fmt.Sprintf(expiryIndexFormat, table)
+}
+
+// IterateRecords calls fn on every record of the table. Differently from GetAllRecords, the whole table is
+// scanned. The iteration stops at the first error returned by fn
+func (db *Redis) IterateRecords(table string, fn func(key, value string) error) error {
+	iter := db.Client.HScan(table, 0, "*", maxScan).Iterator()
+
+	key := ""
+	for i := 0; iter.Next(); i++ {
+		if i%2 == 0 {
+			key = iter.Val()
+			continue
+		}
+		if err := fn(key, iter.Val()); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
 }
 
 // GetAllRecords returns all the records from that table
@@ -146,6 +338,31 @@ func (db *Redis) PipelineExec() error {
 	return err
 }
 
+// Publish sends the message to all the subscribers of the channel
+func (db *Redis) Publish(channel, message string) error {
+	return db.Client.Publish(channel, message).Err()
+}
+
+// Subscribe listens to the channel and returns the received messages together with the function for closing
+// the subscription. The messages channel is closed once the subscription is closed
+func (db *Redis) Subscribe(channel string) (<-chan string, func() error, error) {
+	ps := db.Client.Subscribe(channel)
+	// wait for the confirmation of the subscription
+	if _, err := ps.Receive(); err != nil {
+		ps.Close()
+		return nil, nil, err
+	}
+
+	msgs := make(chan string)
+	go func() {
+		for msg := range ps.Channel() {
+			msgs <- msg.Payload
+		}
+		close(msgs)
+	}()
+	return msgs, ps.Close, nil
+}
+
 // Lock allows to lock the resource
 func (db *Redis) Lock(key string) (bool, error) {
 	res, err := db.Client.SetNX(key, LockOn, TTL).Result()
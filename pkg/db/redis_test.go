@@ -2,8 +2,11 @@ package db
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/rtlnl/phoenix/utils"
 	"github.com/stretchr/testify/assert"
@@ -86,6 +89,7 @@ func TestRedisGetOneNotExists(t *testing.T) {
 	assert.Equal(t, "", val)
 	assert.NotNil(t, err)
 	assert.Equal(t, "key dont-exist not found", err.Error())
+	assert.True(t, errors.Is(err, ErrNotFound))
 }
 
 func TestRedisAddOne(t *testing.T) {
@@ -276,3 +280,260 @@ func TestRedisGetAllRecords(t *testing.T) {
 	assert.Equal(t, 2, len(values))
 	assert.Equal(t, 2, count)
 }
+
+func TestRedisIterateRecords(t *testing.T) {
+	c, err := NewRedisClient(testRedisHost, Password(testRedisPassword))
+	if err != nil {
+		t.Fail()
+	}
+	defer c.Close()
+
+	// more records than the ones returned by GetAllRecords
+	for i := 0; i < 100; i++ {
+		if err := c.AddOne("iterate", strconv.Itoa(i), "[]"); err != nil {
+			t.Fail()
+		}
+	}
+
+	records := map[string]string{}
+	err = c.IterateRecords("iterate", func(key, value string) error {
+		records[key] = value
+		return nil
+	})
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, 100, len(records))
+	assert.Equal(t, "[]", records["42"])
+
+	// stops at the first error
+	err = c.IterateRecords("iterate", func(key, value string) error {
+		return errors.New("stop")
+	})
+	assert.Equal(t, "stop", err.Error())
+}
+
+func TestRedisRenameTable(t *testing.T) {
+	c, err := NewRedisClient(testRedisHost, Password(testRedisPassword))
+	if err != nil {
+		t.Fail()
+	}
+	defer c.Close()
+
+	if err := c.AddOne("renamed", "old", "[]"); err != nil {
+		t.Fail()
+	}
+	if err := c.AddOne("to-rename", "new", "[]"); err != nil {
+		t.Fail()
+	}
+
+	if err := c.RenameTable("to-rename", "renamed"); err != nil {
+		t.Fail()
+	}
+
+	// the destination table is replaced
+	_, err = c.GetOne("renamed", "old")
+	assert.NotNil(t, err)
+	val, err := c.GetOne("renamed", "new")
+	if err != nil {
+		t.Fail()
+	}
+	assert.Equal(t, "[]", val)
+
+	_, err = c.GetOne("to-rename", "new")
+	assert.NotNil(t, err)
+}
+
+func TestRedisPublishSubscribe(t *testing.T) {
+	c, err := NewRedisClient(testRedisHost, Password(testRedisPassword))
+	if err != nil {
+		t.Fail()
+	}
+	defer c.Close()
+
+	msgs, unsubscribe, err := c.Subscribe("news")
+	if err != nil {
+		t.FailNow()
+	}
+
+	if err := c.Publish("news", "hello"); err != nil {
+		t.Fail()
+	}
+
+	select {
+	case msg := <-msgs:
+		assert.Equal(t, "hello", msg)
+	case <-time.After(time.Second):
+		t.Fail()
+	}
+
+	if err := unsubscribe(); err != nil {
+		t.Fail()
+	}
+}
+
+func TestRedisGetMany(t *testing.T) {
+	c, err := NewRedisClient(testRedisHost, Password(testRedisPassword))
+	if err != nil {
+		t.Fail()
+	}
+	defer c.Close()
+
+	if err := c.AddOne("many1", "a", "1"); err != nil {
+		t.Fail()
+	}
+	if err := c.AddOne("many1", "b", "2"); err != nil {
+		t.Fail()
+	}
+	if err := c.AddOne("many2", "c", "3"); err != nil {
+		t.Fail()
+	}
+
+	res, err := c.GetMany(map[string][]string{
+		"many1": {"a", "b", "missing"},
+		"many2": {"c"},
+		"many3": {"d"},
+	})
+	if err != nil {
+		t.FailNow()
+	}
+
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, res["many1"])
+	assert.Equal(t, map[string]string{"c": "3"}, res["many2"])
+	assert.Equal(t, map[string]string{}, res["many3"])
+}
+
+func TestRedisDeleteExpired(t *testing.T) {
+	c, err := NewRedisClient(testRedisHost, Password(testRedisPassword))
+	if err != nil {
+		t.Fail()
+	}
+	defer c.Close()
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := c.AddOne("expiring", strconv.Itoa(i), "[]"); err != nil {
+			t.FailNow()
+		}
+	}
+	if err := c.SetExpiry("expiring", "0", now.Add(-time.Hour)); err != nil {
+		t.FailNow()
+	}
+	if err := c.SetExpiry("expiring", "1", now.Add(-time.Minute)); err != nil {
+		t.FailNow()
+	}
+	if err := c.SetExpiry("expiring", "2", now.Add(time.Hour)); err != nil {
+		t.FailNow()
+	}
+
+	keys, err := c.DeleteExpired("expiring", now, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"0"}, keys)
+
+	keys, err = c.DeleteExpired("expiring", now, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1"}, keys)
+
+	_, err = c.GetOne("expiring", "1")
+	assert.NotNil(t, err)
+	_, err = c.GetOne("expiring", "2")
+	assert.Nil(t, err)
+
+	// removing the expiry keeps the key forever
+	if err := c.RemoveExpiry("expiring", "2"); err != nil {
+		t.FailNow()
+	}
+	keys, err = c.DeleteExpired("expiring", now.Add(2*time.Hour), 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, keys)
+
+	// the expiries follow the table when renamed
+	if err := c.SetExpiry("expiring", "2", now.Add(-time.Hour)); err != nil {
+		t.FailNow()
+	}
+	if err := c.RenameTable("expiring", "expired"); err != nil {
+		t.FailNow()
+	}
+	keys, err = c.DeleteExpired("expired", now, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2"}, keys)
+}
+
+func TestRedisCompareAndSwap(t *testing.T) {
+	c, err := NewRedisClient(testRedisHost, Password(testRedisPassword))
+	if err != nil {
+		t.Fail()
+	}
+	defer c.Close()
+
+	// the key must not exist
+	ok, err := c.CompareAndSwap("swapping", "a", NoChecksum, "1", nil)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = c.CompareAndSwap("swapping", "a", NoChecksum, "2", nil)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// the checksum must match the current value
+	ok, err = c.CompareAndSwap("swapping", "a", Checksum("0"), "2", nil)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	expiresAt := time.Now().Add(-time.Minute)
+	ok, err = c.CompareAndSwap("swapping", "a", Checksum("1"), "2", &expiresAt)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	val, err := c.GetOne("swapping", "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", val)
+
+	ok, err = c.CompareAndSwap("swapping", "b", AnyChecksum, "1", nil)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	ok, err = c.CompareAndDelete("swapping", "a", Checksum("1"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	ok, err = c.CompareAndDelete("swapping", "a", Checksum("2"))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// the expiry is deleted together with the key
+	keys, err := c.DeleteExpired("swapping", time.Now(), 10)
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestRedisAddOneWithExpiry(t *testing.T) {
+	c, err := NewRedisClient(testRedisHost, Password(testRedisPassword))
+	if err != nil {
+		t.Fail()
+	}
+	defer c.Close()
+	defer c.DropTable("withexpiry")
+
+	now := time.Now()
+	expiresAt := now.Add(-time.Minute)
+	if err := c.AddOneWithExpiry("withexpiry", "1", "[]", &expiresAt); err != nil {
+		t.FailNow()
+	}
+	if err := c.AddOneWithExpiry("withexpiry", "2", "[]", &expiresAt); err != nil {
+		t.FailNow()
+	}
+	// storing without expiry keeps the key forever
+	if err := c.AddOneWithExpiry("withexpiry", "2", "[1]", nil); err != nil {
+		t.FailNow()
+	}
+
+	keys, err := c.DeleteExpired("withexpiry", now, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1"}, keys)
+
+	val, err := c.GetOne("withexpiry", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "[1]", val)
+}
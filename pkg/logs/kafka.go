@@ -56,7 +56,7 @@ func NewKafkaLogs(brokers, topic string, options ...func(*sarama.Config)) (Kakfa
 func (k KakfaLog) Write(rl RowLog) error {
 	for _, itemScore := range rl.ItemScores {
 		// create the log message
-		msg, err := CreateLogMessage(rl.PublicationPoint, rl.Campaign, rl.SignalID, itemScore)
+		msg, err := CreateLogMessage(rl, itemScore)
 		if err != nil {
 			return err
 		}
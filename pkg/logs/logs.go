@@ -2,9 +2,11 @@ package logs
 
 import (
 	"encoding/json"
+	"strconv"
 	"time"
 
 	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/utils"
 )
 
 // RowLog is the object that will be written in the logs
@@ -12,6 +14,9 @@ type RowLog struct {
 	PublicationPoint string
 	Campaign         string
 	SignalID         string
+	ModelName        string
+	ServedBy         string
+	Bucket           int
 	ItemScores       []models.ItemScore
 }
 
@@ -21,14 +26,21 @@ type RecommendationLog interface {
 }
 
 // CreateLogMessage append extra information to the item score object
-func CreateLogMessage(publicationPoint, campaign, signalID string, is models.ItemScore) ([]byte, error) {
+func CreateLogMessage(rl RowLog, is models.ItemScore) ([]byte, error) {
 	item := make(map[string]string)
 
 	// append timestamp and signalID
 	item["timestamp"] = time.Now().Format("2006-01-02 15:04:05")
-	item["publicationPoint"] = publicationPoint
-	item["campaign"] = campaign
-	item["signalId"] = signalID
+	item["publicationPoint"] = rl.PublicationPoint
+	item["campaign"] = rl.Campaign
+	item["signalId"] = rl.SignalID
+	item["modelName"] = rl.ModelName
+	item["servedBy"] = utils.GetDefault(rl.ServedBy, rl.ModelName)
+
+	// append the bucket only when the traffic has been split
+	if rl.Bucket > 0 {
+		item["bucket"] = strconv.Itoa(rl.Bucket)
+	}
 
 	// copy the rest
 	for k, v := range is {
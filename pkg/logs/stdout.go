@@ -21,7 +21,7 @@ func NewStdoutLog() StdoutLog {
 func (s StdoutLog) Write(rl RowLog) error {
 	for _, itemScore := range rl.ItemScores {
 		// create the log message
-		msg, err := CreateLogMessage(rl.PublicationPoint, rl.Campaign, rl.SignalID, itemScore)
+		msg, err := CreateLogMessage(rl, itemScore)
 		if err != nil {
 			return err
 		}
@@ -12,4 +12,6 @@ type Metrics interface {
 	StartTimer()
 	// Latency measure the latency from when the request hits the endpoint to the response
 	Latency()
+	// CacheStats keeps track of the hits, the misses, the entries and the memory of the cache
+	CacheStats(hits, misses int64, entries, bytes int)
 }
@@ -9,6 +9,7 @@ type Prometheus struct {
 	RecommendRequests *prometheus.CounterVec
 	RecommendLatency  prometheus.Summary
 	Timer             *prometheus.Timer
+	Cache             *prometheus.GaugeVec
 }
 
 // NewPrometheus instantiates a new prometheus client object
@@ -32,10 +33,21 @@ func NewPrometheus() *Prometheus {
 			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
 		})
 
+	cs := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "phoenix",
+			Subsystem: "public",
+			Name:      "cache",
+			Help:      "Usage of the cache of the recommendations, partitioned by hits, misses, entries and bytes",
+		},
+		[]string{"stat"},
+	)
+
 	prometheus.MustRegister(rr)
 	prometheus.MustRegister(rl)
+	prometheus.MustRegister(cs)
 
-	return &Prometheus{RecommendRequests: rr, RecommendLatency: rl}
+	return &Prometheus{RecommendRequests: rr, RecommendLatency: rl, Cache: cs}
 }
 
 func (p *Prometheus) FailedRequest() {
@@ -60,3 +72,10 @@ func (p *Prometheus) StartTimer() {
 func (p *Prometheus) Latency() {
 	p.Timer.ObserveDuration()
 }
+
+func (p *Prometheus) CacheStats(hits, misses int64, entries, bytes int) {
+	p.Cache.WithLabelValues("hits").Set(float64(hits))
+	p.Cache.WithLabelValues("misses").Set(float64(misses))
+	p.Cache.WithLabelValues("entries").Set(float64(entries))
+	p.Cache.WithLabelValues("bytes").Set(float64(bytes))
+}
@@ -0,0 +1,56 @@
+package public
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rtlnl/phoenix/pkg/cache"
+	"github.com/rtlnl/phoenix/utils"
+)
+
+// CacheStatsResponse is the object that represents the payload of the response with the usage of the cache
+type CacheStatsResponse struct {
+	Stats cache.Stats `json:"stats"`
+}
+
+// CacheEvictResponse is the object that represents the payload of the response when evicting the cache
+type CacheEvictResponse struct {
+	Evicted int    `json:"evicted" description:"number of entries deleted from the cache"`
+	Message string `json:"message"`
+}
+
+// CacheStats returns the usage of the cache of this instance
+func CacheStats(c *gin.Context) {
+	cc := c.MustGet("CacheClient").(cache.Cache)
+
+	utils.Response(c, http.StatusOK, &CacheStatsResponse{
+		Stats: cc.Stats(),
+	})
+}
+
+// EvictCache deletes the entries of the model in the url from the cache of this instance. Without model the
+// whole cache is emptied
+func EvictCache(c *gin.Context) {
+	cc := c.MustGet("CacheClient").(cache.Cache)
+
+	modelName := c.Query("modelName")
+	if modelName == "" {
+		entries := cc.Stats().Entries
+		if !cc.Empty() {
+			utils.ResponseError(c, http.StatusInternalServerError, fmt.Errorf("could not empty the cache"))
+			return
+		}
+		utils.Response(c, http.StatusOK, &CacheEvictResponse{
+			Evicted: entries,
+			Message: "cache emptied",
+		})
+		return
+	}
+
+	utils.Response(c, http.StatusOK, &CacheEvictResponse{
+		Evicted: cc.EvictModel(modelName),
+		Message: fmt.Sprintf("model %s evicted from the cache", modelName),
+	})
+}
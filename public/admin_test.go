@@ -0,0 +1,91 @@
+package public
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/cache"
+)
+
+func mockAdminRequest(method, path, token string) (int, *bytes.Buffer, error) {
+	req, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		return -1, nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w.Code, w.Body, nil
+}
+
+func TestCacheStats(t *testing.T) {
+	code, body, err := mockAdminRequest(http.MethodGet, "/v1/admin/cache", testAdminToken)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body.String(), `"stats":{"hits":`)
+}
+
+func TestCacheStatsInvalidToken(t *testing.T) {
+	code, body, err := mockAdminRequest(http.MethodGet, "/v1/admin/cache", "wrong-token")
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, `{"error":"invalid admin token"}`, body.String())
+}
+
+func TestCacheStatsMissingToken(t *testing.T) {
+	code, _, err := mockAdminRequest(http.MethodGet, "/v1/admin/cache", "")
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestCacheStatsTokenWithoutBearer(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/v1/admin/cache", nil)
+	if err != nil {
+		t.FailNow()
+	}
+	req.Header.Set("Authorization", testAdminToken)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `{"error":"invalid admin token"}`, w.Body.String())
+}
+
+func TestEvictCacheModel(t *testing.T) {
+	cacheClient.Set(cache.Key("admin", "1"), []models.ItemScore{{"item": "1"}})
+	cacheClient.Set(cache.Key("admin", "2"), []models.ItemScore{{"item": "2"}})
+	cacheClient.Set(cache.Key("other", "1"), []models.ItemScore{{"item": "1"}})
+	defer cacheClient.Del(cache.Key("other", "1"))
+
+	code, body, err := mockAdminRequest(http.MethodDelete, "/v1/admin/cache?modelName=admin", testAdminToken)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"evicted":2,"message":"model admin evicted from the cache"}`, body.String())
+
+	_, ok := cacheClient.Get(cache.Key("admin", "1"))
+	assert.False(t, ok)
+	_, ok = cacheClient.Get(cache.Key("other", "1"))
+	assert.True(t, ok)
+}
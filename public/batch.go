@@ -0,0 +1,295 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	zerolog "github.com/rs/zerolog/log"
+
+	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/cache"
+	"github.com/rtlnl/phoenix/pkg/db"
+	"github.com/rtlnl/phoenix/pkg/metrics"
+	"github.com/rtlnl/phoenix/utils"
+)
+
+const (
+	// maxBatchRecommendRequests is the maximum number of recommendations that can be asked in a single batch
+	maxBatchRecommendRequests = 100
+)
+
+// BatchRecommendRequest is the object that represents the payload of the request for the batch recommend endpoint
+type BatchRecommendRequest struct {
+	Requests []BatchRecommendEntry `json:"requests" binding:"required" description:"list of recommendations to fetch"`
+}
+
+// BatchRecommendEntry is a single recommendation asked in a batch request
+type BatchRecommendEntry struct {
+	SignalID         string   `json:"signalId"`
+	PublicationPoint string   `json:"publicationPoint"`
+	Campaign         string   `json:"campaign"`
+	Model            string   `json:"model" description:"model to use instead of the one selected by the container"`
+	Limit            int      `json:"limit" description:"maximum number of recommendations returned. 0 returns all of them"`
+	Offset           int      `json:"offset" description:"number of recommendations to skip"`
+	Fields           []string `json:"fields" description:"fields of the recommendations returned. Empty returns all of them"`
+	Sort             string   `json:"sort" description:"order of the recommendations returned. Empty keeps the order of the model or 'score' for sorting by score"`
+	MinScore         *float64 `json:"minScore" description:"recommendations with a lower score are not returned"`
+}
+
+// BatchRecommendResponse is the object that represents the payload of the response for the batch recommend endpoint
+type BatchRecommendResponse struct {
+	Results []BatchRecommendResult `json:"results"`
+}
+
+// BatchRecommendResult is the outcome of a single entry of the batch. The results are in the same order of the requests
+type BatchRecommendResult struct {
+	SignalID         string `json:"signalId"`
+	PublicationPoint string `json:"publicationPoint"`
+	Campaign         string `json:"campaign"`
+	Status           int    `json:"status" description:"http status code of the single recommendation"`
+	*RecommendResponse
+	Error string `json:"error,omitempty"`
+}
+
+// batchEntry keeps the state of an entry that has not been found in cache
+type batchEntry struct {
+	index     int
+	rr        *RecommendRequest
+	container models.Container
+	model     models.Model
+	modelName string
+	bucket    int
+	chain     []string
+	signalKey string
+}
+
+// RecommendBatch fetches the recommendations for multiple signals or containers in a single request. The entries
+// that are not in cache are fetched from the database with a single pipelined call
+func RecommendBatch(c *gin.Context) {
+	mc := c.MustGet("MetricsClient").(metrics.Metrics)
+	dbc := c.MustGet("DB").(db.DB)
+	cc := c.MustGet("CacheClient").(cache.Cache)
+
+	// start timer for measuring the latency
+	mc.StartTimer()
+	defer mc.Latency()
+
+	var br BatchRecommendRequest
+	if err := c.BindJSON(&br); err != nil {
+		mc.FailedRequest()
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(br.Requests) == 0 || len(br.Requests) > maxBatchRecommendRequests {
+		mc.FailedRequest()
+		utils.ResponseError(c, http.StatusBadRequest, fmt.Errorf("Request format error: requests must contain between 1 and %d entries", maxBatchRecommendRequests))
+		return
+	}
+
+	results := make([]BatchRecommendResult, len(br.Requests))
+	containers := make(map[string]models.Container)
+	mdls := make(map[string]models.Model)
+
+	// resolve container and model of every entry and serve the ones available in cache
+	var pending []batchEntry
+	keys := make(map[string][]string)
+	for i, e := range br.Requests {
+		results[i] = BatchRecommendResult{
+			SignalID:         e.SignalID,
+			PublicationPoint: e.PublicationPoint,
+			Campaign:         e.Campaign,
+		}
+		r := &results[i]
+
+		// validate recommendation parameters with the same rules of the single endpoint
+		rr := new(RecommendRequest)
+		if err := validateRecommendQueryParameters(rr, e.PublicationPoint, e.Campaign, e.SignalID, "false",
+			strconv.Itoa(e.Limit), strconv.Itoa(e.Offset), strings.Join(e.Fields, ","), nil); err != nil {
+			mc.FailedRequest()
+			r.fail(http.StatusBadRequest, err)
+			continue
+		}
+		if err := validateScoreParameters(rr, e.Sort, ""); err != nil {
+			mc.FailedRequest()
+			r.fail(http.StatusBadRequest, err)
+			continue
+		}
+		rr.MinScore = e.MinScore
+
+		// get container from DB only once per batch
+		cn := models.ContainerUniqueName(rr.PublicationPoint, rr.Campaign)
+		container, ok := containers[cn]
+		if !ok {
+			ct, err := models.GetContainer(rr.PublicationPoint, rr.Campaign, dbc)
+			if err != nil {
+				mc.NotFoundRequest()
+				r.fail(http.StatusNotFound, err)
+				continue
+			}
+			containers[cn] = ct
+			container = ct
+		}
+
+		// blended containers read every linked model so they are not part of the pipelined lookup
+		if container.Blend != nil && e.Model == "" {
+			names, itemsScore, err := getBlendedRecommendations(cc, dbc, container, rr)
+			if err != nil {
+				if _, ok := err.(notFoundError); ok {
+					mc.NotFoundRequest()
+					r.fail(http.StatusNotFound, err)
+					continue
+				}
+				mc.FailedRequest()
+				r.fail(http.StatusInternalServerError, err)
+				continue
+			}
+			mc.SuccessRequest()
+			resp := buildResponse(c, container, rr, names, names, 0, itemsScore)
+			resp.Blend = container.Blend.Strategy
+			r.succeed(resp)
+			continue
+		}
+
+		// get model name either from the request, traffic split or default
+		modelName, bucket, err := getModelName(e.Model, container, rr.SignalID)
+		if err != nil {
+			mc.NotFoundRequest()
+			r.fail(http.StatusNotFound, err)
+			continue
+		}
+
+		// get model from DB only once per batch
+		m, ok := mdls[modelName]
+		if !ok {
+			gm, err := models.GetModel(modelName, dbc)
+			if err != nil {
+				mc.NotFoundRequest()
+				r.fail(http.StatusNotFound, err)
+				continue
+			}
+			mdls[modelName] = gm
+			m = gm
+		}
+
+		// validate signal
+		if !m.CorrectSignalFormat(rr.SignalID) {
+			mc.FailedRequest()
+			r.fail(http.StatusBadRequest, errors.New("signal is not formatted correctly"))
+			continue
+		}
+		if err := m.ValidateSignalParts(rr.SignalID); err != nil {
+			mc.FailedRequest()
+			r.fail(http.StatusBadRequest, err)
+			continue
+		}
+
+		signalKey := m.SignalKey(rr.SignalID)
+		if is, ok := cc.Get(cache.Key(modelName, signalKey)); ok {
+			mc.SuccessRequest()
+			r.succeed(buildResponse(c, container, rr, modelName, modelName, bucket, is))
+			continue
+		}
+
+		pending = append(pending, batchEntry{
+			index:     i,
+			rr:        rr,
+			container: container,
+			model:     m,
+			modelName: modelName,
+			bucket:    bucket,
+			chain:     container.ModelChain(modelName),
+			signalKey: signalKey,
+		})
+		keys[m.DataTable()] = append(keys[m.DataTable()], signalKey)
+	}
+
+	if len(pending) == 0 {
+		utils.Response(c, http.StatusOK, &BatchRecommendResponse{Results: results})
+		return
+	}
+
+	// fetch all the missing entries of the selected models in one round trip
+	values, err := dbc.GetMany(keys)
+	if err != nil {
+		for _, p := range pending {
+			mc.FailedRequest()
+			results[p.index].fail(http.StatusInternalServerError, err)
+		}
+		utils.Response(c, http.StatusOK, &BatchRecommendResponse{Results: results})
+		return
+	}
+
+	for _, p := range pending {
+		r := &results[p.index]
+
+		servedBy, itemsScore, err := p.fetch(cc, dbc, values)
+		if err != nil {
+			if _, ok := err.(notFoundError); ok {
+				mc.NotFoundRequest()
+				r.fail(http.StatusNotFound, err)
+				continue
+			}
+			mc.FailedRequest()
+			r.fail(http.StatusInternalServerError, err)
+			continue
+		}
+
+		mc.SuccessRequest()
+		r.succeed(buildResponse(c, p.container, p.rr, p.modelName, servedBy, p.bucket, itemsScore))
+	}
+
+	utils.Response(c, http.StatusOK, &BatchRecommendResponse{Results: results})
+}
+
+// fetch returns the recommendations of the entry from the values fetched in batch. When the selected model has
+// no entry for the signal, the fallback models of the container are tried one by one
+func (p *batchEntry) fetch(cc cache.Cache, dbc db.DB, values map[string]map[string]string) (string, []models.ItemScore, error) {
+	v, ok := values[p.model.DataTable()][p.signalKey]
+	if !ok {
+		return p.fallback(cc, dbc)
+	}
+
+	// convert single entry from string to []models.ItemScore
+	itemsScore, expiresAt, err := models.DeserializeEntry(v)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not deserialize object. error: %s", err.Error())
+	}
+
+	// expired entries are served as missing until the sweeper deletes them
+	if models.IsExpired(expiresAt) {
+		return p.fallback(cc, dbc)
+	}
+
+	// store in cache only the entries that never expire since the cache does not know the expiry
+	if expiresAt == nil {
+		key := cache.Key(p.modelName, p.signalKey)
+		if ok := cc.Set(key, itemsScore); !ok {
+			// if an error occur we simply log it and continue
+			zerolog.Error().Msgf("failed to store key %s in cache", key)
+		}
+	}
+	return p.modelName, itemsScore, nil
+}
+
+// fallback tries the fallback models of the container when the selected model cannot serve the signal
+func (p *batchEntry) fallback(cc cache.Cache, dbc db.DB) (string, []models.ItemScore, error) {
+	if len(p.chain) > 1 {
+		return getRecommendations(cc, dbc, p.model, p.chain, p.rr)
+	}
+	return "", nil, notFoundError{fmt.Errorf("key %s not found", p.signalKey)}
+}
+
+func (r *BatchRecommendResult) succeed(resp *RecommendResponse) {
+	r.Status = http.StatusOK
+	r.RecommendResponse = resp
+}
+
+func (r *BatchRecommendResult) fail(status int, err error) {
+	r.Status = status
+	r.Error = err.Error()
+}
@@ -0,0 +1,78 @@
+package public
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rtlnl/phoenix/models"
+)
+
+func TestRecommendBatch(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	if _, err := models.NewModel("batchmodel", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewContainer("batch", "campaign", []string{"batchmodel"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	UploadTestData(t, dbc, "testdata/test_published_model_data.jsonl", "batchmodel")
+
+	payload := `{"requests":[
+		{"publicationPoint":"batch","campaign":"campaign","signalId":"500083","limit":1},
+		{"publicationPoint":"batch","campaign":"campaign","signalId":"1252","fields":["item"]},
+		{"publicationPoint":"batch","campaign":"campaign","signalId":"missing"},
+		{"publicationPoint":"nobatch","campaign":"campaign","signalId":"500083"},
+		{"publicationPoint":"batch","campaign":"campaign","signalId":"500083","offset":-1}
+	]}`
+
+	code, body, err := MockRequest(http.MethodPost, "/v1/recommend/batch", strings.NewReader(payload))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"results\":["+
+		"{\"signalId\":\"500083\",\"publicationPoint\":\"batch\",\"campaign\":\"campaign\",\"status\":200,\"modelName\":\"batchmodel\",\"recommendations\":[{\"item\":\"6456\",\"score\":\"0.6\"}]},"+
+		"{\"signalId\":\"1252\",\"publicationPoint\":\"batch\",\"campaign\":\"campaign\",\"status\":200,\"modelName\":\"batchmodel\",\"recommendations\":[{\"item\":\"2345\"},{\"item\":\"1471\"},{\"item\":\"7876\"}]},"+
+		"{\"signalId\":\"missing\",\"publicationPoint\":\"batch\",\"campaign\":\"campaign\",\"status\":404,\"error\":\"key missing not found\"},"+
+		"{\"signalId\":\"500083\",\"publicationPoint\":\"nobatch\",\"campaign\":\"campaign\",\"status\":404,\"error\":\"container with publication point nobatch and campaign campaign not found\"},"+
+		"{\"signalId\":\"500083\",\"publicationPoint\":\"batch\",\"campaign\":\"campaign\",\"status\":400,\"error\":\"Request format error: offset must be a positive number\"}"+
+		"]}", string(b))
+
+	// the second request is served from the cache
+	code, body, err = MockRequest(http.MethodPost, "/v1/recommend/batch", strings.NewReader(`{"requests":[{"publicationPoint":"batch","campaign":"campaign","signalId":"1252","limit":1}]}`))
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"results\":[{\"signalId\":\"1252\",\"publicationPoint\":\"batch\",\"campaign\":\"campaign\",\"status\":200,\"modelName\":\"batchmodel\",\"recommendations\":[{\"item\":\"2345\",\"score\":\"0.286\"}]}]}", string(b))
+}
+
+func TestRecommendBatchFailValidation(t *testing.T) {
+	code, _, err := MockRequest(http.MethodPost, "/v1/recommend/batch", strings.NewReader(`{"requests":[]}`))
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,45 @@
+package public
+
+import (
+	"fmt"
+	"strings"
+
+	zerolog "github.com/rs/zerolog/log"
+
+	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/cache"
+	"github.com/rtlnl/phoenix/pkg/db"
+)
+
+// getBlendedRecommendations fetches the recommendations of all the models linked to the container and merges them
+// following the blend strategy of the container. Models that are not available or that have no entry for the signal
+// are left out of the blend. It returns the names of the models that contributed separated by a comma
+func getBlendedRecommendations(cc cache.Cache, dbc db.DB, container models.Container, rr *RecommendRequest) (string, []models.ItemScore, error) {
+	var blended []string
+	items := make(map[string][]models.ItemScore)
+	for _, modelName := range container.Models {
+		m, err := models.GetModel(modelName, dbc)
+		if err != nil {
+			zerolog.Error().Msgf("blended model %s not available. error: %s", modelName, err.Error())
+			continue
+		}
+		// the linked models might use different signal formats
+		if !m.CorrectSignalFormat(rr.SignalID) || m.ValidateSignalParts(rr.SignalID) != nil {
+			continue
+		}
+		_, is, err := getRecommendations(cc, dbc, m, []string{modelName}, rr)
+		if err != nil {
+			if _, ok := err.(notFoundError); ok {
+				continue
+			}
+			return "", nil, err
+		}
+		blended = append(blended, modelName)
+		items[modelName] = is
+	}
+
+	if len(blended) == 0 {
+		return "", nil, notFoundError{fmt.Errorf("signal %s not found in the models of publicationPoint %s and campaign %s", rr.SignalID, container.PublicationPoint, container.Campaign)}
+	}
+	return strings.Join(blended, ","), container.Blend.Merge(blended, items), nil
+}
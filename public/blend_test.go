@@ -0,0 +1,94 @@
+package public
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rtlnl/phoenix/models"
+)
+
+func TestRecommendBlend(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	if _, err := models.NewModel("blendone", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewModel("blendtwo", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	container, err := models.NewContainer("blend", "campaign", []string{"blendone", "blendtwo"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	if err := container.SetBlend(models.Blend{Strategy: models.BlendRoundRobin}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	UploadTestData(t, dbc, "testdata/test_published_model_data.jsonl", "blendone")
+	if err := dbc.AddOne("blendtwo", "500083", `[{"item":"1252","score":"0.9"},{"item":"42","score":"0.1"}]`); err != nil {
+		t.FailNow()
+	}
+
+	code, body, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=blend&campaign=campaign&signalId=500083", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"modelName\":\"blendone,blendtwo\",\"blend\":\"round-robin\",\"recommendations\":[{\"item\":\"6456\",\"score\":\"0.6\"},{\"item\":\"1252\",\"score\":\"0.9\"},{\"item\":\"7876\",\"score\":\"0.987\"},{\"item\":\"42\",\"score\":\"0.1\"}]}", string(b))
+
+	// only the models with an entry for the signal are blended
+	code, body, err = MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=blend&campaign=campaign&signalId=1252", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"modelName\":\"blendone\",\"blend\":\"round-robin\",\"recommendations\":[{\"item\":\"2345\",\"score\":\"0.286\"},{\"item\":\"1471\",\"score\":\"0.345\"},{\"item\":\"7876\",\"score\":\"0.987\"}]}", string(b))
+
+	// requesting a model skips the blending
+	code, body, err = MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=blend&campaign=campaign&signalId=500083&model=blendtwo", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"modelName\":\"blendtwo\",\"recommendations\":[{\"item\":\"1252\",\"score\":\"0.9\"},{\"item\":\"42\",\"score\":\"0.1\"}]}", string(b))
+
+	// none of the models has the signal
+	code, body, err = MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=blend&campaign=campaign&signalId=404", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "{\"error\":\"signal 404 not found in the models of publicationPoint blend and campaign campaign\"}", string(b))
+}
@@ -3,6 +3,8 @@ package public
 import (
 	ginprometheus "github.com/banzaicloud/go-gin-prometheus"
 	"github.com/gin-gonic/gin"
+
+	"github.com/rtlnl/phoenix/middleware"
 )
 
 // Public is the struct that will retain the server for ingesting the
@@ -11,8 +13,8 @@ type Public struct {
 	App *gin.Engine
 }
 
-// NewPublicAPI creates a new object holding the Gin Server
-func NewPublicAPI(middlewares ...gin.HandlerFunc) (*Public, error) {
+// NewPublicAPI creates a new object holding the Gin Server. The admin routes require the admin token as bearer token
+func NewPublicAPI(adminToken string, middlewares ...gin.HandlerFunc) (*Public, error) {
 	// Creates a router without any middleware by default
 	r := gin.Default()
 
@@ -35,6 +37,12 @@ func NewPublicAPI(middlewares ...gin.HandlerFunc) (*Public, error) {
 	// Public API v1
 	v1 := r.Group("v1")
 	v1.GET("/recommend", Recommend)
+	v1.POST("/recommend/batch", RecommendBatch)
+
+	// Admin routes
+	ad := v1.Group("/admin", middleware.AdminToken(adminToken))
+	ad.GET("/cache", CacheStats)
+	ad.DELETE("/cache", EvictCache)
 
 	return &Public{
 		App: r,
@@ -20,7 +20,7 @@ func TestNewPublicAPI(t *testing.T) {
 	middlewares = append(middlewares, middleware.DB(dbc))
 	middlewares = append(middlewares, middleware.RecommendationLogs(rl))
 
-	p, err := NewPublicAPI(middlewares...)
+	p, err := NewPublicAPI("", middlewares...)
 	if err != nil {
 		t.Fail()
 	}
@@ -1,16 +1,19 @@
 package public
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/gin-gonic/gin"
 	zerolog "github.com/rs/zerolog/log"
 
 	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/blocklist"
 	"github.com/rtlnl/phoenix/pkg/cache"
 	"github.com/rtlnl/phoenix/pkg/db"
 	"github.com/rtlnl/phoenix/pkg/logs"
@@ -20,18 +23,39 @@ import (
 
 // RecommendRequest is the object that represents the payload of the request for the recommend endpoint
 type RecommendRequest struct {
-	SignalID         string `json:"signalId"`
-	PublicationPoint string `json:"publicationPoint"`
-	Campaign         string `json:"campaign"`
-	FlushCache       bool   `json:"flushCache"`
+	SignalID         string            `json:"signalId"`
+	SignalParts      map[string]string `json:"signalParts" description:"named parts of the signal used when signalId is missing, i.e. {'userId': '1', 'articleId': '2'}"`
+	PublicationPoint string            `json:"publicationPoint"`
+	Campaign         string            `json:"campaign"`
+	FlushCache       bool              `json:"flushCache"`
+	Limit            int               `json:"limit" description:"maximum number of recommendations returned. 0 returns all of them"`
+	Offset           int               `json:"offset" description:"number of recommendations to skip"`
+	Fields           []string          `json:"fields" description:"fields of the recommendations returned. Empty returns all of them"`
+	Sort             string            `json:"sort" description:"order of the recommendations returned. Empty keeps the order of the model or 'score' for sorting by score"`
+	MinScore         *float64          `json:"minScore" description:"recommendations with a lower score are not returned"`
 }
 
+const (
+	// sortByScore sorts the recommendations from the highest to the lowest score
+	sortByScore = "score"
+)
+
+// recommendParameters are the query parameters of the recommend endpoint. Any other query parameter is a named
+// part of the signal
+var recommendParameters = []string{"publicationPoint", "campaign", "signalId", "flushCache", "limit", "offset", "fields", "model", "sort", "minScore"}
+
 // RecommendResponse is the object that represents the payload of the response for the recommend endpoint
 type RecommendResponse struct {
 	ModelName       string      `json:"modelName"`
+	ServedBy        string      `json:"servedBy,omitempty" description:"fallback model that served the recommendations when the selected model had no entry for the signal"`
+	Bucket          int         `json:"bucket,omitempty" description:"traffic bucket of the signal when the container splits the traffic between models"`
+	Blend           string      `json:"blend,omitempty" description:"strategy used for blending the models when the container serves all of them at once"`
 	Recommendations interface{} `json:"recommendations" description:""`
 }
 
+// flights coalesces the concurrent reads of the database for the same key missing from the cache
+var flights cache.Flight
+
 // rrPool is in charged of Pooling eventual requests in coming. This will help to reduce the alloc/s
 // and efficiently improve the garbage collection operations. rr is short for recommend-request
 var rrPool = sync.Pool{
@@ -56,9 +80,17 @@ func Recommend(c *gin.Context) {
 	cp := c.DefaultQuery("campaign", "")
 	sID := c.DefaultQuery("signalId", "")
 	fc := c.DefaultQuery("flushCache", "false")
+	lm := c.DefaultQuery("limit", "0")
+	of := c.DefaultQuery("offset", "0")
+	fs := c.DefaultQuery("fields", "")
 
 	// validate recommendation parameters
-	if err := validateRecommendQueryParameters(rr, pp, cp, sID, fc); err != nil {
+	if err := validateRecommendQueryParameters(rr, pp, cp, sID, fc, lm, of, fs, signalParts(c)); err != nil {
+		mc.FailedRequest()
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := validateScoreParameters(rr, c.DefaultQuery("sort", ""), c.DefaultQuery("minScore", "")); err != nil {
 		mc.FailedRequest()
 		utils.ResponseError(c, http.StatusBadRequest, err)
 		return
@@ -72,8 +104,56 @@ func Recommend(c *gin.Context) {
 		return
 	}
 
-	// get model name either from Tucson or URL
-	modelName, err := getModelName(c, container)
+	// get caching layer client
+	cc := c.MustGet("CacheClient").(cache.Cache)
+
+	requested := c.DefaultQuery("model", "")
+
+	// the traffic split and the blending need the signal before a model is selected, hence the signal is first
+	// composed with the format of the model that the container would serve without traffic split
+	if len(rr.SignalParts) > 0 {
+		ref := getModelFromURL(requested, container)
+		if ref == "" {
+			ref = getDefaultModelName(container)
+		}
+		rm, err := models.GetModel(ref, dbc)
+		if err != nil {
+			mc.NotFoundRequest()
+			utils.ResponseError(c, http.StatusNotFound, err)
+			return
+		}
+		if err := buildSignal(rm, rr); err != nil {
+			mc.FailedRequest()
+			utils.ResponseError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	// serve all the linked models at once unless a single model is requested
+	if container.Blend != nil && requested == "" {
+		names, itemsScore, err := getBlendedRecommendations(cc, dbc, container, rr)
+		if err != nil {
+			if _, ok := err.(notFoundError); ok {
+				mc.NotFoundRequest()
+				utils.ResponseError(c, http.StatusNotFound, err)
+				return
+			}
+			mc.FailedRequest()
+			utils.ResponseError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		// track a successful request
+		mc.SuccessRequest()
+
+		resp := buildResponse(c, container, rr, names, names, 0, itemsScore)
+		resp.Blend = container.Blend.Strategy
+		utils.Response(c, http.StatusOK, resp)
+		return
+	}
+
+	// get model name either from URL, traffic split or default
+	modelName, bucket, err := getModelName(requested, container, rr.SignalID)
 	if err != nil {
 		mc.NotFoundRequest()
 		utils.ResponseError(c, http.StatusNotFound, err)
@@ -88,101 +168,250 @@ func Recommend(c *gin.Context) {
 		return
 	}
 
+	// compose the signal again in case the selected model uses a different format
+	if err := buildSignal(m, rr); err != nil {
+		mc.FailedRequest()
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
+
 	// validate signal
 	if !m.CorrectSignalFormat(rr.SignalID) {
 		mc.FailedRequest()
 		utils.ResponseError(c, http.StatusBadRequest, errors.New("signal is not formatted correctly"))
 		return
 	}
+	if err := m.ValidateSignalParts(rr.SignalID); err != nil {
+		mc.FailedRequest()
+		utils.ResponseError(c, http.StatusBadRequest, err)
+		return
+	}
 
-	// get caching layer client
-	cc := c.MustGet("CacheClient").(cache.Cache)
-	// get logging client
-	lt := c.MustGet("RecommendationLog").(logs.RecommendationLog)
-	// compose key for the cache
-	key := fmt.Sprintf("%s#%s", modelName, rr.SignalID)
-
-	// check if value is in cache only if flushing is not specified
-	if is, ok := cc.Get(key); ok && !rr.FlushCache {
-		// write logs
-		lt.Write(logs.RowLog{
-			PublicationPoint: rr.PublicationPoint,
-			Campaign:         rr.Campaign,
-			SignalID:         rr.SignalID,
-			ItemScores:       is,
-		})
-
-		// track a successful request
+	// serve the cached recommendations of the selected model as they are when the response does not change them
+	if raw, ok := getCachedResponse(c, cc, container, m, rr); ok {
 		mc.SuccessRequest()
 
-		// return response
 		utils.Response(c, http.StatusOK, &RecommendResponse{
 			ModelName:       modelName,
-			Recommendations: is,
+			Bucket:          bucket,
+			Recommendations: json.RawMessage(raw),
 		})
+		writeRawLog(c, rr, modelName, bucket, raw)
 		return
 	}
 
-	// get the recommended values
-	r, err := dbc.GetOne(modelName, rr.SignalID)
-	if err != nil {
-		mc.NotFoundRequest()
-		utils.ResponseError(c, http.StatusNotFound, err)
-		return
-	}
-
-	// convert single entry from string to []models.ItemScore
-	itemsScore, err := models.DeserializeItemScoreArray(r)
+	// walk through the model chain until a model has recommendations for the signal
+	servedBy, itemsScore, err := getRecommendations(cc, dbc, m, container.ModelChain(modelName), rr)
 	if err != nil {
+		if _, ok := err.(notFoundError); ok {
+			mc.NotFoundRequest()
+			utils.ResponseError(c, http.StatusNotFound, err)
+			return
+		}
 		mc.FailedRequest()
-		utils.ResponseError(c, http.StatusInternalServerError, fmt.Errorf("could not deserialize object. error: %s", err.Error()))
+		utils.ResponseError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	// store in cache
-	if ok := cc.Set(key, itemsScore); !ok {
-		// if an error occur we simply log it and continue
-		zerolog.Error().Msgf("failed to store key %s in cache", key)
+	// track a successful request
+	mc.SuccessRequest()
+
+	utils.Response(c, http.StatusOK, buildResponse(c, container, rr, modelName, servedBy, bucket, itemsScore))
+}
+
+// buildResponse applies the rules of the container and the global blocklist to the recommendations, slices the
+// page requested and logs the recommendations served
+func buildResponse(c *gin.Context, container models.Container, rr *RecommendRequest, modelName, servedBy string, bucket int, itemsScore []models.ItemScore) *RecommendResponse {
+	// get logging client
+	lt := c.MustGet("RecommendationLog").(logs.RecommendationLog)
+
+	// apply the business rules of the container and remove the globally blocked items
+	itemsScore = container.Rules.Apply(itemsScore)
+	itemsScore = c.MustGet("Blocklist").(*blocklist.Blocklist).Filter(itemsScore)
+
+	// keep only the items above the minimum score and sort them if requested
+	if rr.MinScore != nil {
+		itemsScore = models.FilterByScore(itemsScore, *rr.MinScore)
 	}
+	if rr.Sort == sortByScore {
+		itemsScore = models.SortByScore(itemsScore)
+	}
+
+	// slice the page requested by the client. The full list stays in cache
+	itemsScore = paginate(itemsScore, rr.Offset, rr.Limit)
 
 	// write logs in a separate thread for not blocking the server
+	rl := logs.RowLog{
+		PublicationPoint: rr.PublicationPoint,
+		Campaign:         rr.Campaign,
+		SignalID:         rr.SignalID,
+		ModelName:        modelName,
+		ServedBy:         servedBy,
+		Bucket:           bucket,
+		ItemScores:       itemsScore,
+	}
 	go func() {
-		err = lt.Write(logs.RowLog{
-			PublicationPoint: rr.PublicationPoint,
-			Campaign:         rr.Campaign,
-			SignalID:         rr.SignalID,
-			ItemScores:       itemsScore,
-		})
 		// log error if it fails the logging
-		if err != nil {
+		if err := lt.Write(rl); err != nil {
 			zerolog.Error().Msg(err.Error())
 		}
 	}()
 
-	// track a successful request
-	mc.SuccessRequest()
-
-	utils.Response(c, http.StatusOK, &RecommendResponse{
+	resp := &RecommendResponse{
 		ModelName:       modelName,
-		Recommendations: itemsScore,
-	})
+		Bucket:          bucket,
+		Recommendations: selectFields(itemsScore, rr.Fields),
+	}
+	// report the fallback model only when the selected model could not serve the signal
+	if servedBy != modelName {
+		resp.ServedBy = servedBy
+	}
+	return resp
 }
 
-func getModelName(c *gin.Context, container models.Container) (string, error) {
+// getCachedResponse returns the cached recommendations of the selected model already serialized. They are returned
+// only when the response would contain them unchanged, that is without rules, blocked items, score filtering,
+// sorting, pagination or field selection
+func getCachedResponse(c *gin.Context, cc cache.Cache, container models.Container, m models.Model, rr *RecommendRequest) ([]byte, bool) {
+	if rr.FlushCache || rr.MinScore != nil || rr.Sort != "" || rr.Offset > 0 || rr.Limit > 0 || len(rr.Fields) > 0 {
+		return nil, false
+	}
+	if (container.Rules != nil && !container.Rules.IsEmpty()) || !c.MustGet("Blocklist").(*blocklist.Blocklist).IsEmpty() {
+		return nil, false
+	}
+	return cc.GetBytes(cache.Key(m.Name, m.SignalKey(rr.SignalID)))
+}
+
+// writeRawLog logs the serialized recommendations served. They are deserialized in a separate thread for
+// not blocking the server
+func writeRawLog(c *gin.Context, rr *RecommendRequest, modelName string, bucket int, raw []byte) {
+	lt := c.MustGet("RecommendationLog").(logs.RecommendationLog)
+
+	rl := logs.RowLog{
+		PublicationPoint: rr.PublicationPoint,
+		Campaign:         rr.Campaign,
+		SignalID:         rr.SignalID,
+		ModelName:        modelName,
+		ServedBy:         modelName,
+		Bucket:           bucket,
+	}
+	go func() {
+		if err := json.Unmarshal(raw, &rl.ItemScores); err != nil {
+			zerolog.Error().Msgf("could not deserialize the recommendations to log. error: %s", err.Error())
+			return
+		}
+		// log error if it fails the logging
+		if err := lt.Write(rl); err != nil {
+			zerolog.Error().Msg(err.Error())
+		}
+	}()
+}
+
+// notFoundError is returned when none of the models in the chain has recommendations for the signal
+type notFoundError struct {
+	error
+}
+
+// getRecommendations returns the recommendations of the first model in the chain that has an entry for the signal
+// together with the name of the model that served them. The first model of the chain is the selected one
+func getRecommendations(cc cache.Cache, dbc db.DB, selected models.Model, chain []string, rr *RecommendRequest) (string, []models.ItemScore, error) {
+	var nf error
+	for i, modelName := range chain {
+		m := selected
+		if i > 0 {
+			fm, err := models.GetModel(modelName, dbc)
+			if err != nil {
+				zerolog.Error().Msgf("fallback model %s not available. error: %s", modelName, err.Error())
+				continue
+			}
+			m = fm
+		}
+		// popular models store the same list for every signal
+		signalKey := m.SignalKey(rr.SignalID)
+
+		// compose key for the cache
+		key := cache.Key(modelName, signalKey)
+
+		// check if value is in cache only if flushing is not specified
+		if is, ok := cc.Get(key); ok && !rr.FlushCache {
+			return modelName, is, nil
+		}
+
+		// concurrent misses of the same key share a single read of the database
+		v, err, _ := flights.Do(key, func() (interface{}, error) {
+			return loadRecommendations(cc, dbc, m, key, signalKey)
+		})
+		if err != nil {
+			// keep the error of the selected model and try the next one in the chain
+			if e, ok := err.(notFoundError); ok {
+				if nf == nil {
+					nf = e.error
+				}
+				continue
+			}
+			return "", nil, err
+		}
+		return modelName, v.([]models.ItemScore), nil
+	}
+	return "", nil, notFoundError{nf}
+}
+
+// loadRecommendations reads the recommendations of the signal from the data of the model and stores them in
+// cache. The items returned are shared with the cache and with the concurrent requests of the same key, hence
+// rules, blocklist, sorting, blending and field selection always return new slices and maps instead of modifying them
+func loadRecommendations(cc cache.Cache, dbc db.DB, m models.Model, key, signalKey string) ([]models.ItemScore, error) {
+	// get the recommended values
+	// only a missing key walks the fallback chain, the failures of the database are internal errors
+	r, err := dbc.GetOne(m.DataTable(), signalKey)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, notFoundError{err}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// convert single entry from string to []models.ItemScore
+	itemsScore, expiresAt, err := models.DeserializeEntry(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not deserialize object. error: %s", err.Error())
+	}
+
+	// expired entries are served as missing until the sweeper deletes them
+	if models.IsExpired(expiresAt) {
+		return nil, notFoundError{fmt.Errorf("key %s not found", signalKey)}
+	}
+
+	// store in cache only the entries that never expire since the cache does not know the expiry
+	if expiresAt == nil {
+		if ok := cc.Set(key, itemsScore); !ok {
+			// if an error occur we simply log it and continue
+			zerolog.Error().Msgf("failed to store key %s in cache", key)
+		}
+	}
+	return itemsScore, nil
+}
+
+func getModelName(requested string, container models.Container, signalID string) (string, int, error) {
 	// check URL
-	modelName := getModelFromURL(c.DefaultQuery("model", ""), container)
+	modelName := getModelFromURL(requested, container)
+	if !utils.IsStringEmpty(modelName) {
+		return modelName, 0, nil
+	}
+
+	// check traffic split
+	modelName, bucket := container.WeightedModel(signalID)
 	if !utils.IsStringEmpty(modelName) {
-		return modelName, nil
+		return modelName, bucket, nil
 	}
 
 	// check default model
 	modelName = getDefaultModelName(container)
 	if !utils.IsStringEmpty(modelName) {
-		return modelName, nil
+		return modelName, 0, nil
 	}
 
 	// model is empty
-	return "", fmt.Errorf("model %s not available in publicationPoint %s and campaign %s", modelName, container.PublicationPoint, container.Campaign)
+	return "", 0, fmt.Errorf("model %s not available in publicationPoint %s and campaign %s", modelName, container.PublicationPoint, container.Campaign)
 }
 
 func getModelFromURL(modelName string, container models.Container) string {
@@ -196,16 +425,43 @@ func getModelFromURL(modelName string, container models.Container) string {
 }
 
 func getDefaultModelName(container models.Container) string {
+	if container.DefaultModel != "" {
+		return container.DefaultModel
+	}
+	// without an explicit default model the first linked model is used
 	if len(container.Models) > 0 {
-		// TODO: define potential default model in the future
-		// return the first for now
 		return container.Models[0]
 	}
 	return ""
 }
 
-func validateRecommendQueryParameters(rr *RecommendRequest, publicationPoint, campaign, signalID, flushCache string) error {
-	if publicationPoint == "" || signalID == "" || campaign == "" {
+// signalParts returns the query parameters that are not parameters of the recommend endpoint
+func signalParts(c *gin.Context) map[string]string {
+	parts := make(map[string]string)
+	for k, v := range c.Request.URL.Query() {
+		if utils.StringInSlice(k, recommendParameters) || len(v) == 0 || v[0] == "" {
+			continue
+		}
+		parts[k] = v[0]
+	}
+	return parts
+}
+
+// buildSignal composes the signal of the request from its named parts using the format of the model in input
+func buildSignal(m models.Model, rr *RecommendRequest) error {
+	if len(rr.SignalParts) == 0 {
+		return nil
+	}
+	s, err := m.BuildSignal(rr.SignalParts)
+	if err != nil {
+		return fmt.Errorf("Request format error: %s", err.Error())
+	}
+	rr.SignalID = s
+	return nil
+}
+
+func validateRecommendQueryParameters(rr *RecommendRequest, publicationPoint, campaign, signalID, flushCache, limit, offset, fields string, parts map[string]string) error {
+	if publicationPoint == "" || campaign == "" || (signalID == "" && len(parts) == 0) {
 		return errors.New("Request format error: publicationPoint, campaign or signalId are missing")
 	}
 
@@ -214,11 +470,82 @@ func validateRecommendQueryParameters(rr *RecommendRequest, publicationPoint, ca
 	rr.Campaign = campaign
 	rr.SignalID = signalID
 
+	// the signal is composed from its named parts only when it is not given
+	rr.SignalParts = nil
+	if signalID == "" {
+		rr.SignalParts = parts
+	}
+
 	b, err := strconv.ParseBool(flushCache)
 	if err != nil {
 		return err
 	}
 	rr.FlushCache = b
 
+	l, err := strconv.Atoi(limit)
+	if err != nil || l < 0 {
+		return errors.New("Request format error: limit must be a positive number")
+	}
+	rr.Limit = l
+
+	o, err := strconv.Atoi(offset)
+	if err != nil || o < 0 {
+		return errors.New("Request format error: offset must be a positive number")
+	}
+	rr.Offset = o
+
+	rr.Fields = nil
+	if fields != "" {
+		rr.Fields = utils.RemoveEmptyValueInSlice(strings.Split(fields, ","))
+	}
+
+	return nil
+}
+
+// validateScoreParameters validates the sorting and the minimum score of the recommendations
+func validateScoreParameters(rr *RecommendRequest, sort, minScore string) error {
+	if sort != "" && sort != sortByScore {
+		return fmt.Errorf("Request format error: sort must be %s", sortByScore)
+	}
+	rr.Sort = sort
+
+	rr.MinScore = nil
+	if minScore != "" {
+		ms, err := strconv.ParseFloat(minScore, 64)
+		if err != nil {
+			return errors.New("Request format error: minScore must be a number")
+		}
+		rr.MinScore = &ms
+	}
 	return nil
 }
+
+// paginate returns the items between offset and offset+limit. A limit equal to 0 returns all the items after
+// the offset
+func paginate(items []models.ItemScore, offset, limit int) []models.ItemScore {
+	if offset >= len(items) {
+		return []models.ItemScore{}
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// selectFields returns the items with only the fields in input
+func selectFields(items []models.ItemScore, fields []string) []models.ItemScore {
+	if len(fields) == 0 {
+		return items
+	}
+	selected := make([]models.ItemScore, len(items))
+	for i, is := range items {
+		selected[i] = make(models.ItemScore, len(fields))
+		for _, f := range fields {
+			if v, ok := is[f]; ok {
+				selected[i][f] = v
+			}
+		}
+	}
+	return selected
+}
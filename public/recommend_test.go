@@ -4,8 +4,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/cache"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -220,6 +222,323 @@ func TestRecommendWrongSignal(t *testing.T) {
 	assert.Equal(t, "{\"error\":\"key jjkk_767 not found\"}", string(b))
 }
 
+func TestRecommendWeighted(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	for _, name := range []string{"weightedA", "weightedB"} {
+		if _, err := models.NewModel(name, "", []string{"signal"}, dbc); err != nil {
+			t.FailNow()
+		}
+		UploadTestData(t, dbc, "testdata/test_published_model_data.jsonl", name)
+	}
+
+	container, err := models.NewContainer("weighted", "campaign", []string{"weightedA", "weightedB"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	// send all the traffic to the second model
+	if err := container.SetWeights(map[string]int{"weightedB": 1}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	code, body, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=weighted&campaign=campaign&signalId=500083", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"modelName\":\"weightedB\",\"bucket\":1,\"recommendations\":[{\"item\":\"6456\",\"score\":\"0.6\"},{\"item\":\"1252\",\"score\":\"0.345\"},{\"item\":\"7876\",\"score\":\"0.987\"}]}", string(b))
+}
+
+func TestRecommendFallback(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	if _, err := models.NewModel("personal", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewModel("generic", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	container, err := models.NewContainer("fallback", "campaign", []string{"personal"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	if err := container.SetModelChain("personal", []string{"generic"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	// only the fallback model has the data
+	UploadTestData(t, dbc, "testdata/test_published_model_data.jsonl", "generic")
+
+	code, body, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=fallback&campaign=campaign&signalId=500083", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"modelName\":\"personal\",\"servedBy\":\"generic\",\"recommendations\":[{\"item\":\"6456\",\"score\":\"0.6\"},{\"item\":\"1252\",\"score\":\"0.345\"},{\"item\":\"7876\",\"score\":\"0.987\"}]}", string(b))
+
+	// none of the models in the chain has the signal
+	code, body, err = MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=fallback&campaign=campaign&signalId=404", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "{\"error\":\"key 404 not found\"}", string(b))
+}
+
+func TestRecommendPopularFallback(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	if _, err := models.NewModel("coldstart", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewPopularModel("mostviewed", dbc); err != nil {
+		t.FailNow()
+	}
+
+	container, err := models.NewContainer("coldstart", "campaign", []string{"coldstart"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	if err := container.SetModelChain("", []string{"mostviewed"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if err := dbc.AddOne("mostviewed", models.PopularSignalID, `[{"item":"1","score":"0.9"}]`); err != nil {
+		t.FailNow()
+	}
+
+	code, body, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=coldstart&campaign=campaign&signalId=123", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"modelName\":\"coldstart\",\"servedBy\":\"mostviewed\",\"recommendations\":[{\"item\":\"1\",\"score\":\"0.9\"}]}", string(b))
+}
+
+func TestRecommendRules(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	if _, err := models.NewModel("ruled", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	container, err := models.NewContainer("rules", "campaign", []string{"ruled"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	minScore := 0.5
+	if err := container.SetRules(models.Rules{BlockedItems: []string{"1252"}, MinScore: &minScore}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	UploadTestData(t, dbc, "testdata/test_published_model_data.jsonl", "ruled")
+
+	code, body, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=rules&campaign=campaign&signalId=500083", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"modelName\":\"ruled\",\"recommendations\":[{\"item\":\"6456\",\"score\":\"0.6\"},{\"item\":\"7876\",\"score\":\"0.987\"}]}", string(b))
+}
+
+func TestRecommendBlocklist(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	if _, err := models.NewModel("blocked", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewContainer("blocklist", "campaign", []string{"blocked"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	UploadTestData(t, dbc, "testdata/test_published_model_data.jsonl", "blocked")
+
+	// store the recommendations in cache
+	code, _, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=blocklist&campaign=campaign&signalId=967", nil)
+	if err != nil {
+		t.Fail()
+	}
+	assert.Equal(t, http.StatusOK, code)
+
+	if err := models.BlockItems([]string{"1252"}, dbc); err != nil {
+		t.FailNow()
+	}
+	defer func() {
+		models.UnblockItems([]string{"1252"}, dbc)
+		blocklistClient.Reload()
+	}()
+	if err := blocklistClient.Reload(); err != nil {
+		t.FailNow()
+	}
+
+	// the cached recommendations are filtered as well
+	code, body, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=blocklist&campaign=campaign&signalId=967", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"modelName\":\"blocked\",\"recommendations\":[{\"item\":\"87608\",\"score\":\"0.356\"},{\"item\":\"1429\",\"score\":\"0.987\"}]}", string(b))
+}
+
+func TestRecommendCachedResponse(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	if _, err := models.NewModel("raw", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewContainer("raw", "campaign", []string{"raw"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	UploadTestData(t, dbc, "testdata/test_published_model_data.jsonl", "raw")
+
+	// the serialized recommendations in cache are written to the response as they are
+	cacheClient.SetBytes(cache.Key("raw", "500083"), []byte(`[{"item":"1","score":"0.1"}]`))
+	defer cacheClient.Del(cache.Key("raw", "500083"))
+
+	code, body, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=raw&campaign=campaign&signalId=500083", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"modelName\":\"raw\",\"recommendations\":[{\"item\":\"1\",\"score\":\"0.1\"}]}", body.String())
+
+	// the page requested needs the recommendations deserialized
+	code, body, err = MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=raw&campaign=campaign&signalId=500083&limit=1", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"modelName\":\"raw\",\"recommendations\":[{\"item\":\"1\",\"score\":\"0.1\"}]}", body.String())
+}
+
+func TestRecommendPagination(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	if _, err := models.NewModel("paginated", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewContainer("pagination", "campaign", []string{"paginated"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	UploadTestData(t, dbc, "testdata/test_published_model_data.jsonl", "paginated")
+
+	tests := map[string]struct {
+		query    string
+		expected string
+	}{
+		"limit": {
+			query:    "&limit=2",
+			expected: "{\"modelName\":\"paginated\",\"recommendations\":[{\"item\":\"4562\",\"score\":\"0.656\"},{\"item\":\"1252\",\"score\":\"0.345\"}]}",
+		},
+		"offset": {
+			query:    "&offset=1&limit=1",
+			expected: "{\"modelName\":\"paginated\",\"recommendations\":[{\"item\":\"1252\",\"score\":\"0.345\"}]}",
+		},
+		"offset out of range": {
+			query:    "&offset=10",
+			expected: "{\"modelName\":\"paginated\",\"recommendations\":[]}",
+		},
+		"fields": {
+			query:    "&offset=2&fields=item",
+			expected: "{\"modelName\":\"paginated\",\"recommendations\":[{\"item\":\"12345\"}]}",
+		},
+	}
+	for testName, test := range tests {
+		t.Logf("Running test case %s", testName)
+		code, body, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=pagination&campaign=campaign&signalId=1471"+test.query, nil)
+		if err != nil {
+			t.Fail()
+		}
+
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Fail()
+		}
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, test.expected, string(b))
+	}
+}
+
+func TestRecommendFailValidationLimit(t *testing.T) {
+	code, body, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=hello&campaign=homepage&signalId=500083&limit=-1", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "{\"error\":\"Request format error: limit must be a positive number\"}", string(b))
+}
+
 func BenchmarkRecommend(b *testing.B) {
 	b.StopTimer()
 
@@ -243,3 +562,214 @@ func BenchmarkRecommend(b *testing.B) {
 		MockRequestBenchmark(b, http.MethodGet, "/v1/recommend?publicationPoint=publication1&campaign=campaign&signalId=500083", nil)
 	}
 }
+
+func TestRecommendSignalParts(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	if _, err := models.NewModel("contextual", "|", []string{"userId", "articleId"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewContainer("context", "campaign", []string{"contextual"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if err := dbc.AddOne("contextual", "1|2", `[{"item":"6456","score":"0.6"}]`); err != nil {
+		t.FailNow()
+	}
+
+	code, body, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=context&campaign=campaign&articleId=2&userId=1", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"modelName\":\"contextual\",\"recommendations\":[{\"item\":\"6456\",\"score\":\"0.6\"}]}", string(b))
+
+	// a part of the signal is missing
+	code, body, err = MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=context&campaign=campaign&userId=1", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "{\"error\":\"Request format error: signal part articleId is missing\"}", string(b))
+
+	// a part of the signal contains the concatenator
+	code, body, err = MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=context&campaign=campaign&userId=1%7C3&articleId=2", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "{\"error\":\"Request format error: signal part userId cannot contain the concatenator |\"}", string(b))
+}
+
+func TestRecommendWrongSignalPart(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	m, err := models.NewModel("typedsignal", "", []string{"userId"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	if err := m.SetSignalSchema(map[string]models.SignalPart{"userId": {Type: models.SignalPartInteger}}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewContainer("typed", "campaign", []string{"typedsignal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	code, body, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=typed&campaign=campaign&signalId=abc", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "{\"error\":\"signal part userId must be an integer, got abc\"}", string(b))
+}
+
+func TestRecommendSortScore(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	if _, err := models.NewModel("scored", "", []string{"signal"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewContainer("scores", "campaign", []string{"scored"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	UploadTestData(t, dbc, "testdata/test_published_model_data.jsonl", "scored")
+
+	code, body, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=scores&campaign=campaign&signalId=500083&sort=score&minScore=0.5", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"modelName\":\"scored\",\"recommendations\":[{\"item\":\"7876\",\"score\":\"0.987\"},{\"item\":\"6456\",\"score\":\"0.6\"}]}", string(b))
+
+	code, body, err = MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=scores&campaign=campaign&signalId=500083&sort=item", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "{\"error\":\"Request format error: sort must be score\"}", string(b))
+
+	code, body, err = MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=scores&campaign=campaign&signalId=500083&minScore=high", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "{\"error\":\"Request format error: minScore must be a number\"}", string(b))
+}
+
+func TestRecommendExpired(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	defer c()
+
+	// Test object creation
+	m, err := models.NewModel("expiring", "", []string{"signal"}, dbc)
+	if err != nil {
+		t.FailNow()
+	}
+
+	if _, err := models.NewContainer("expiry", "campaign", []string{"expiring"}, dbc); err != nil {
+		t.FailNow()
+	}
+
+	// the sweeper has not deleted the expired signal yet
+	expired := time.Now().Add(-time.Minute)
+	if _, err := m.StoreRecommendations("1", []models.ItemScore{{"item": "a"}}, &expired, dbc); err != nil {
+		t.FailNow()
+	}
+
+	valid := time.Now().Add(time.Hour)
+	if _, err := m.StoreRecommendations("2", []models.ItemScore{{"item": "b"}}, &valid, dbc); err != nil {
+		t.FailNow()
+	}
+
+	code, body, err := MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=expiry&campaign=campaign&signalId=1", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "{\"error\":\"key 1 not found\"}", string(b))
+
+	code, body, err = MockRequest(http.MethodGet, "/v1/recommend?publicationPoint=expiry&campaign=campaign&signalId=2", nil)
+	if err != nil {
+		t.Fail()
+	}
+
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		t.Fail()
+	}
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "{\"modelName\":\"expiring\",\"recommendations\":[{\"item\":\"b\"}]}", string(b))
+}
+
+func TestLoadRecommendationsDatabaseError(t *testing.T) {
+	dbc, c := GetTestRedisClient()
+	c()
+
+	// a failure of the database is not served as a missing signal
+	m := models.Model{Name: "unreachable"}
+	_, err := loadRecommendations(cacheClient, dbc, m, cache.Key("unreachable", "1"), "1")
+	if assert.Error(t, err) {
+		_, ok := err.(notFoundError)
+		assert.False(t, ok)
+	}
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/rtlnl/phoenix/middleware"
 	"github.com/rtlnl/phoenix/models"
+	"github.com/rtlnl/phoenix/pkg/blocklist"
 	"github.com/rtlnl/phoenix/pkg/cache"
 	"github.com/rtlnl/phoenix/pkg/db"
 	"github.com/rtlnl/phoenix/pkg/logs"
@@ -26,9 +27,14 @@ import (
 var (
 	testDBHost     = utils.GetEnv("DB_HOST", "127.0.0.1:6379")
 	testDBPassword = utils.GetEnv("DB_PASSWORD", "")
+	testAdminToken = "test-token"
 )
 
-var router *gin.Engine
+var (
+	router          *gin.Engine
+	blocklistClient *blocklist.Blocklist
+	cacheClient     *cache.AllegroBigCache
+)
 
 func TestMain(m *testing.M) {
 	tearUp()
@@ -43,7 +49,7 @@ func tearUp() {
 	router = gin.New()
 	router.RedirectTrailingSlash = true
 
-	cacheClient, _ := cache.NewAllegroBigCache(cache.Shards(1024),
+	cacheClient, _ = cache.NewAllegroBigCache(cache.Shards(1024),
 		cache.LifeWindow(time.Minute*10),
 		cache.MaxEntriesInWindow(1000*10*60),
 		cache.MaxEntrySize(500),
@@ -60,15 +66,27 @@ func tearUp() {
 	// create metrics client
 	mc := metrics.NewPrometheus()
 
+	// create the global blocklist
+	blocklistClient, err = blocklist.NewBlocklist(dbc)
+	if err != nil {
+		panic(err)
+	}
+
 	router.Use(middleware.DB(dbc))
 
 	router.Use(middleware.RecommendationLogs(logs.NewStdoutLog()))
 	router.Use(middleware.Cache(cacheClient))
 	router.Use(middleware.Metrics(mc))
+	router.Use(middleware.Blocklist(blocklistClient))
 
 	// subscribe route Recommend here due to multiple tests on this route
 	// it avoids a panic error for registering the route multiple times
 	router.GET("/v1/recommend", Recommend)
+	router.POST("/v1/recommend/batch", RecommendBatch)
+
+	admin := router.Group("/v1/admin", middleware.AdminToken(testAdminToken))
+	admin.GET("/cache", CacheStats)
+	admin.DELETE("/cache", EvictCache)
 }
 
 func tearDown() {
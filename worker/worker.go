@@ -3,6 +3,7 @@ package worker
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/adjust/rmq/v3"
@@ -22,6 +23,10 @@ const (
 	consumerTag   = "phoenix-consumer-tag"
 	unackedLimit  = 10
 	pollDuration  = 15 * time.Second
+	// TaskUpload is the type of the tasks uploading the data of a model from S3
+	TaskUpload = "upload"
+	// TaskRemoveItem is the type of the tasks removing an item from all the signals of a model
+	TaskRemoveItem = "remove_item"
 )
 
 // Worker encapsulate the queueing system
@@ -38,8 +43,9 @@ type TaskConsumer struct {
 	before time.Time
 }
 
-// TaskPayload is the struct that contains the payload for consuming the task
+// TaskPayload is the struct that contains the payload for consuming the task. Tasks without a type are uploads
 type TaskPayload struct {
+	Type         string `json:"type"`
 	DBURL        string `json:"db_url"`
 	DBPassword   string `json:"db_password"`
 	AWSRegion    string `json:"aws_region"`
@@ -49,6 +55,7 @@ type TaskPayload struct {
 	S3Key        string `json:"s3_key"`
 	ModelName    string `json:"model_name"`
 	BatchID      string `json:"batch_id"`
+	ItemID       string `json:"item_id"`
 }
 
 // New creates a new worker object
@@ -71,7 +78,7 @@ func New(rc *redis.Client, workerName, queueName string) (*Worker, error) {
 	return &Worker{Queue: queue, Consumer: cs}, nil
 }
 
-// Consume will execute the operation of batch uploading the data in Redis
+// Consume will execute the operation requested by the task
 func (c TaskConsumer) Consume(delivery rmq.Delivery) {
 	var task *TaskPayload
 	if err := json.Unmarshal([]byte(delivery.Payload()), &task); err != nil {
@@ -79,9 +86,6 @@ func (c TaskConsumer) Consume(delivery rmq.Delivery) {
 		return
 	}
 
-	sess := aws.NewAWSSession(task.AWSRegion, task.S3Endpoint, task.S3DisableSSL)
-	s := db.NewS3Client(&db.S3Bucket{Bucket: task.S3Bucket, ACL: ""}, sess)
-
 	// create batch operator
 	dbc, err := db.NewRedisClient(task.DBURL, db.Password(task.DBPassword))
 	if err != nil {
@@ -101,30 +105,44 @@ func (c TaskConsumer) Consume(delivery rmq.Delivery) {
 	// create batch operator
 	bo := batch.NewOperator(dbc, m)
 
+	switch task.Type {
+	case TaskRemoveItem:
+		err = bo.RemoveItem(task.BatchID, task.ItemID)
+	default:
+		err = uploadFromS3(bo, task)
+	}
+	if err != nil {
+		log.Error().Msg(err.Error())
+		delivery.Reject()
+		return
+	}
+	// message processed correctly
+	delivery.Ack()
+}
+
+// uploadFromS3 uploads the file specified in the task in a new version of the model
+func uploadFromS3(bo *batch.Operator, task *TaskPayload) error {
+	sess := aws.NewAWSSession(task.AWSRegion, task.S3Endpoint, task.S3DisableSSL)
+	s := db.NewS3Client(&db.S3Bucket{Bucket: task.S3Bucket, ACL: ""}, sess)
+
 	// check if file exists
 	if s.ExistsObject(task.S3Key) == false {
 		bo.SetStatus(task.BatchID, batch.BulkFailed)
-		log.Error().Msgf("key %s not founds in S3", task.S3Key)
-		delivery.Reject()
-		return
+		return fmt.Errorf("key %s not founds in S3", task.S3Key)
 	}
 
 	// download the file
 	f, err := s.GetObject(task.S3Key)
 	if err != nil {
 		bo.SetStatus(task.BatchID, batch.BulkFailed)
-		log.Error().Msg(err.Error())
-		delivery.Reject()
-		return
+		return err
 	}
 
 	if err := bo.UploadDataFromFile(f, task.BatchID); err != nil {
 		bo.SetStatus(task.BatchID, batch.BulkFailed)
-		delivery.Reject()
-		return
+		return err
 	}
-	// message processed correctly
-	delivery.Ack()
+	return nil
 }
 
 // Consume instructs the worker to consuming the messages